@@ -73,7 +73,7 @@ const Watchlist = () => {
         setCurrentFilm(null);
         setError(true);
         // Check if it's a genre-specific error
-        if (selectedGenres.length > 0 && data.error && data.error.includes("no films")) {
+        if (data.code === "no_films_for_genres") {
           setErrorType("no_films_for_genres");
         } else {
           setErrorType("user_not_found");
@@ -12,9 +12,13 @@ export CGO_ENABLED=0
 echo "Cleaning previous builds..."
 rm -f main
 
+# Stamp the binary with the version and commit reported by /healthz and /readyz
+VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}
+COMMIT=${COMMIT:-$(git rev-parse HEAD 2>/dev/null)}
+
 # Build the application
-echo "Building Go API server..."
-go build -ldflags="-s -w" -o main .
+echo "Building Go API server $VERSION..."
+go build -ldflags="-s -w -X go-backend/internal/version.Version=$VERSION -X go-backend/internal/version.Commit=$COMMIT" -o main .
 
 # Make binary executable
 chmod +x main
@@ -24,4 +28,4 @@ echo "Binary size:"
 du -h main
 
 echo "To run: ./main"
-echo "To test: curl http://localhost:8081/health" 
\ No newline at end of file
+echo "To test: curl http://localhost:8081/healthz" 
\ No newline at end of file
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+
+	"go-backend/internal/ai"
+	"go-backend/internal/config"
+	"go-backend/internal/scraper"
+	"go-backend/internal/store"
+	"go-backend/internal/usage"
+)
+
+const commandUsage = `Usage: go-backend [command]
+
+With no command the API server is started.
+
+Commands:
+  serve [flags]                      run the API server, see 'serve -h' for its flags
+  pick <user> [-genres ids] [-count n]
+                                     pick n random films from a watchlist (default 1)
+  recommend <prompt> [-mode m] [-taste t] [-exclude titles] [-count n] [-db path]
+                                     ask Gemini for recommendations, charging the
+                                     tokens to "cli" against the daily budget
+  scrape <user> [-format f]          print a whole watchlist as json (default), csv,
+                                     jsonl or letterboxd (Letterboxd's import CSV)
+
+  migrate up [-db path]              apply pending store migrations
+  migrate down [-db path] [-steps n] revert the newest n migrations (default 1)
+  migrate status [-db path]          show the current and latest schema version
+  store vacuum [-db path]            rebuild the store file, reclaiming free space
+  store export [-db path] [file]     write every row as JSON lines (default stdout)
+  store import [-db path] [file]     read rows written by export (default stdin)
+  apikey create <name> [-db path]    issue an API key, AI usage is then charged to it
+  apikey revoke <prefix> [-db path]  revoke the key starting with prefix
+
+Flags may come before or after arguments and take one or two dashes.
+-db defaults to the configured store path, see Configuration in the README.
+`
+
+// runCommand runs a management command and returns the process exit code
+func runCommand(args []string) int {
+	var err error
+	switch args[0] {
+	case "serve":
+		err = serve(args[1:])
+	case "pick":
+		err = pickCommand(args[1:])
+	case "recommend":
+		err = recommendCommand(args[1:])
+	case "scrape":
+		err = scrapeCommand(args[1:])
+	case "migrate":
+		err = migrateCommand(args[1:])
+	case "store":
+		err = storeCommand(args[1:])
+	case "apikey":
+		err = apiKeyCommand(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(commandUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], commandUsage)
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// openStoreForCommand parses the shared -db flag plus any extra flags, and opens the store
+// without migrating it so the migrate commands stay in control of the schema
+func openStoreForCommand(name string, args []string, extra func(*flag.FlagSet)) (*store.SQLite, []string, error) {
+	cfg, err := config.FromEnv(configChecks...)
+	if err != nil {
+		return nil, nil, err
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	dbPath := fs.String("db", cfg.StorePath, "path to the SQLite store")
+	if extra != nil {
+		extra(fs)
+	}
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := store.OpenSQLite(context.Background(), *dbPath, store.SkipMigrations())
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, rest, nil
+}
+
+// parseFlags parses flags wherever they appear among the arguments, unlike fs.Parse which
+// stops at the first argument, so "pick alice -count 3" works. It returns the arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// printJSON writes v to stdout, indented for reading in a terminal
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func pickCommand(args []string) error {
+	fs := flag.NewFlagSet("pick", flag.ContinueOnError)
+	genres := fs.String("genres", "", "comma-separated TMDB genre IDs, e.g. 27,53")
+	count := fs.Int("count", 1, "how many films to pick")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("pick needs exactly one Letterboxd username")
+	}
+	if *count < 1 {
+		return fmt.Errorf("-count must be at least 1")
+	}
+
+	films, err := scraper.ScrapeWatchlist(rest[0], *genres)
+	if err != nil {
+		return err
+	}
+	if len(films) == 0 {
+		return fmt.Errorf("no films found in %s's watchlist", rest[0])
+	}
+
+	// Distinct films, in random order, as many as were asked for
+	picks := make([]scraper.Film, 0, *count)
+	for _, i := range rand.Perm(len(films)) {
+		if len(picks) == *count {
+			break
+		}
+		picks = append(picks, films[i])
+	}
+	return printJSON(picks)
+}
+
+func recommendCommand(args []string) error {
+	fs := flag.NewFlagSet("recommend", flag.ContinueOnError)
+	opts := ai.RecommendOptions{}
+	fs.StringVar(&opts.Mode, "mode", "", "prompt mode, e.g. hidden-gem")
+	fs.StringVar(&opts.Taste, "taste", "", "a description of the viewer's taste")
+	exclude := fs.String("exclude", "", "comma-separated titles to avoid")
+	fs.IntVar(&opts.Count, "count", 1, "how many films to recommend, 1 to 5")
+	dbPath := fs.String("db", "", "path to the SQLite store the tokens are charged in")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("recommend needs exactly one prompt, quote it if it has spaces")
+	}
+	if opts.Count < 1 || opts.Count > 5 {
+		return fmt.Errorf("-count must be between 1 and 5")
+	}
+	for _, title := range strings.Split(*exclude, ",") {
+		if title = strings.TrimSpace(title); title != "" {
+			opts.Exclusions = append(opts.Exclusions, title)
+		}
+	}
+
+	cfg, err := config.FromEnv(configChecks...)
+	if err != nil {
+		return err
+	}
+	if *dbPath == "" {
+		*dbPath = cfg.StorePath
+	}
+	// Calls from the command line are charged to the same ledger and budget as the server's
+	ctx := usage.WithClient(context.Background(), "cli")
+	db, err := store.OpenSQLite(ctx, *dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ledger := usage.NewLedger(cfg.DailyTokenBudget)
+	if err := ledger.Persist(ctx, db); err != nil {
+		return fmt.Errorf("failed to load AI usage: %w", err)
+	}
+
+	promptStore, err := ai.LoadPrompts(cfg.Prompts.Dir)
+	if err != nil {
+		return err
+	}
+	recommender, err := ai.NewRecommender(ctx, aiConfig(cfg), promptStore)
+	if err != nil {
+		return err
+	}
+	defer recommender.Close()
+	recommender.TrackUsage(ledger)
+
+	movies, err := recommender.Recommend(ctx, rest[0], opts)
+	if err != nil {
+		return err
+	}
+	return printJSON(movies)
+}
+
+func scrapeCommand(args []string) error {
+	fs := flag.NewFlagSet("scrape", flag.ContinueOnError)
+	format := fs.String("format", "json", "output format: json, csv, jsonl or letterboxd")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("scrape needs exactly one Letterboxd username")
+	}
+	if *format != "json" && !slices.Contains(scraper.ExportFormats, *format) {
+		return fmt.Errorf("unknown format %q, expected json, %s", *format, strings.Join(scraper.ExportFormats, ", "))
+	}
+
+	films, err := scraper.GetWatchlist(rest[0])
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		return printJSON(films)
+	}
+	return scraper.WriteFilms(os.Stdout, *format, films)
+}
+
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate needs one of: up, down, status")
+	}
+	switch args[0] {
+	case "up", "down", "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected one of: up, down, status", args[0])
+	}
+	ctx := context.Background()
+
+	steps := 1
+	db, _, err := openStoreForCommand("migrate "+args[0], args[1:], func(fs *flag.FlagSet) {
+		if args[0] == "down" {
+			fs.IntVar(&steps, "steps", 1, "how many migrations to revert")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Store is already up to date")
+		}
+		for _, name := range applied {
+			fmt.Printf("Applied %s\n", name)
+		}
+	case "down":
+		if steps < 1 {
+			return fmt.Errorf("-steps must be at least 1")
+		}
+		reverted, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to revert")
+		}
+		for _, name := range reverted {
+			fmt.Printf("Reverted %s\n", name)
+		}
+	case "status":
+		current, err := db.Version(ctx)
+		if err != nil {
+			return err
+		}
+		latest, err := store.LatestVersion()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Schema version %d, latest %d\n", current, latest)
+		if current < latest {
+			fmt.Println("Run 'go-backend migrate up' to apply pending migrations")
+		}
+	}
+	return nil
+}
+
+func storeCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("store needs one of: vacuum, export, import")
+	}
+	switch args[0] {
+	case "vacuum", "export", "import":
+	default:
+		return fmt.Errorf("unknown store command %q, expected one of: vacuum, export, import", args[0])
+	}
+	ctx := context.Background()
+
+	db, rest, err := openStoreForCommand("store "+args[0], args[1:], nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	file := "-"
+	if len(rest) > 0 {
+		file = rest[0]
+	}
+
+	switch args[0] {
+	case "vacuum":
+		if err := db.Vacuum(ctx); err != nil {
+			return err
+		}
+		fmt.Println("Store vacuumed")
+
+	case "export":
+		var w io.Writer = os.Stdout
+		if file != "-" {
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		n, err := db.Export(ctx, w)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d rows\n", n)
+
+	case "import":
+		var r io.Reader = os.Stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		n, err := db.Import(ctx, r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Imported %d rows\n", n)
+	}
+	return nil
+}
+
+func apiKeyCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("apikey needs one of: create, revoke")
+	}
+	switch args[0] {
+	case "create", "revoke":
+	default:
+		return fmt.Errorf("unknown apikey command %q, expected one of: create, revoke", args[0])
+	}
+	ctx := context.Background()
+
+	db, rest, err := openStoreForCommand("apikey "+args[0], args[1:], nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if len(rest) != 1 {
+		return fmt.Errorf("apikey %s needs exactly one argument", args[0])
+	}
+
+	switch args[0] {
+	case "create":
+		key, k, err := db.CreateAPIKey(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Created key %s for %s, it is only shown once:\n", k.Prefix, k.Name)
+		fmt.Println(key)
+
+	case "revoke":
+		if err := db.RevokeAPIKey(ctx, rest[0]); errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("no active key with prefix %q", rest[0])
+		} else if err != nil {
+			return err
+		}
+		fmt.Println("Key revoked")
+	}
+	return nil
+}
@@ -73,6 +73,8 @@ docker image prune -f
 
 # Build and start production deployment
 print_status "Building and starting production deployment..."
+export VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}
+export COMMIT=${COMMIT:-$(git rev-parse HEAD 2>/dev/null)}
 docker-compose -f docker-compose.prod.yml up --build -d
 
 # Wait for container to be ready
@@ -95,7 +97,7 @@ max_attempts=30
 attempt=0
 
 while [ $attempt -lt $max_attempts ]; do
-    if curl -f http://localhost:3000/health > /dev/null 2>&1; then
+    if curl -f http://localhost:3000/readyz > /dev/null 2>&1; then
         print_success "Health check passed!"
         break
     fi
@@ -116,7 +118,7 @@ fi
 print_status "Testing API endpoints..."
 
 # Test health endpoint
-if curl -f http://localhost:3000/health > /dev/null 2>&1; then
+if curl -f http://localhost:3000/readyz > /dev/null 2>&1; then
     print_success "Health endpoint: OK"
 else
     print_error "Health endpoint: FAILED"
@@ -136,7 +138,7 @@ print_success "Production deployment completed successfully!"
 echo ""
 echo "Deployment Information:"
 echo "  Service URL: http://localhost:3000"
-echo "  Health Check: http://localhost:3000/health"
+echo "  Health Check: http://localhost:3000/readyz"
 echo "  API Endpoints:"
 echo "    - GET /watchlist?username=<username>&genres=<genres>"
 echo "    - GET /recommend?prompt=<prompt>"
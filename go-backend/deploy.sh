@@ -26,6 +26,8 @@ cd ../go-backend
 
 # Build and start Go backend
 echo "🔨 Building and starting Go backend..."
+export VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}
+export COMMIT=${COMMIT:-$(git rev-parse HEAD 2>/dev/null)}
 docker-compose up --build -d
 
 # Wait for health check
@@ -34,10 +36,10 @@ sleep 10
 
 # Test health endpoint
 echo "🧪 Testing health endpoint..."
-if curl -f http://localhost:3000/health > /dev/null 2>&1; then
+if curl -f http://localhost:3000/readyz > /dev/null 2>&1; then
     echo "✅ Go backend is running successfully!"
     echo "🌐 Available at: http://localhost:3000"
-    echo "📊 Health check: http://localhost:3000/health"
+    echo "📊 Health check: http://localhost:3000/readyz"
 else
     echo "❌ Go backend failed to start properly"
     echo "📋 Checking logs..."
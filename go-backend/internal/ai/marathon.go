@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MarathonMode is the prompt template used to programme marathons
+const MarathonMode = "marathon"
+
+// Marathon limits, a double feature up to a five film all-nighter
+const (
+	MinMarathonFilms = 2
+	MaxMarathonFilms = 5
+)
+
+// MarathonFilm is one slot in a marathon
+type MarathonFilm struct {
+	MovieData
+	Order   int `json:"order"`
+	Runtime int `json:"runtime"`
+}
+
+// Marathon is a themed programme of films in watch order
+type Marathon struct {
+	Theme        string         `json:"theme"`
+	Films        []MarathonFilm `json:"films"`
+	TotalRuntime int            `json:"total_runtime"`
+}
+
+// Tally renumbers the films in watch order and adds up their runtimes
+func (m *Marathon) Tally() {
+	m.TotalRuntime = 0
+	for i := range m.Films {
+		m.Films[i].Order = i + 1
+		m.TotalRuntime += m.Films[i].Runtime
+	}
+}
+
+// Marathon programmes opts.Count films sharing theme. opts.Candidates restricts the choice.
+func (r *Recommender) Marathon(ctx context.Context, theme string, opts RecommendOptions) (*Marathon, error) {
+	theme, err := r.policy.Check(theme)
+	if err != nil {
+		return nil, err
+	}
+	opts, err = r.policy.CheckOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Mode = MarathonMode
+	if opts.Count < MinMarathonFilms || opts.Count > MaxMarathonFilms {
+		return nil, fmt.Errorf("a marathon needs between %d and %d films", MinMarathonFilms, MaxMarathonFilms)
+	}
+
+	responseText, err := r.generate(ctx, theme, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AI marathon: %w", err)
+	}
+
+	marathon, err := parseMarathonResponse(responseText, opts.Count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	return marathon, nil
+}
+
+// parseMarathonResponse reads the model's marathon, which must have at least count films.
+// Any beyond count are dropped.
+func parseMarathonResponse(responseText string, count int) (*Marathon, error) {
+	// Clean up the response - remove markdown formatting if present
+	jsonString := strings.TrimSpace(responseText)
+	jsonString = strings.TrimPrefix(jsonString, "```json")
+	jsonString = strings.TrimSuffix(jsonString, "```")
+	jsonString = strings.TrimSpace(jsonString)
+
+	var marathon Marathon
+	if err := json.Unmarshal([]byte(jsonString), &marathon); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	if len(marathon.Films) < count {
+		return nil, fmt.Errorf("invalid marathon: asked for %d films, got %d", count, len(marathon.Films))
+	}
+	marathon.Films = marathon.Films[:count]
+	for _, film := range marathon.Films {
+		if film.Name == "" || film.Slug == "" {
+			return nil, fmt.Errorf("invalid movie data: missing name or slug")
+		}
+	}
+
+	marathon.Tally()
+	return &marathon, nil
+}
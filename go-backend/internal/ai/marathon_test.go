@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// marathonReply is a model reply programming n films of 100 minutes each
+func marathonReply(n int) string {
+	films := make([]string, n)
+	for i := range films {
+		films[i] = fmt.Sprintf(`{"name": "Film %d", "year": "2000", "slug": "https://letterboxd.com/film/film-%d/", "runtime": 100}`, i+1, i+1)
+	}
+	return "```json\n" + `{"theme": "Heists", "films": [` + strings.Join(films, ", ") + `]}` + "\n```"
+}
+
+func TestParseMarathonResponse(t *testing.T) {
+	marathon, err := parseMarathonResponse(marathonReply(3), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marathon.Theme != "Heists" || len(marathon.Films) != 3 || marathon.TotalRuntime != 300 {
+		t.Errorf("marathon = %+v", marathon)
+	}
+	for i, film := range marathon.Films {
+		if film.Order != i+1 {
+			t.Errorf("film %d has order %d", i+1, film.Order)
+		}
+	}
+}
+
+func TestParseMarathonResponseCount(t *testing.T) {
+	// Extra films are cut to the count asked for, and left out of the total
+	marathon, err := parseMarathonResponse(marathonReply(MaxMarathonFilms), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(marathon.Films) != 2 || marathon.Films[1].Name != "Film 2" || marathon.TotalRuntime != 200 {
+		t.Errorf("marathon of 5 cut to 2 = %+v", marathon)
+	}
+
+	// Too few films is a bad reply, not a shorter marathon
+	for _, n := range []int{0, 1, 3} {
+		if _, err := parseMarathonResponse(marathonReply(n), 4); err == nil {
+			t.Errorf("reply with %d films accepted for a marathon of 4", n)
+		}
+	}
+}
+
+func TestParseMarathonResponseInvalid(t *testing.T) {
+	for _, reply := range []string{
+		"not json",
+		`{"theme": "Heists", "films": [{"name": "Heat"}, {"name": "Thief", "slug": "https://letterboxd.com/film/thief/"}]}`,
+	} {
+		if _, err := parseMarathonResponse(reply, 2); err == nil {
+			t.Errorf("parseMarathonResponse(%q) succeeded", reply)
+		}
+	}
+}
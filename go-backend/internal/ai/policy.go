@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Stable refusal codes returned to clients when a prompt is rejected
+const (
+	CodePromptEmpty     = "prompt_empty"
+	CodePromptTooLong   = "prompt_too_long"
+	CodePromptInjection = "prompt_injection"
+	CodePromptOffTopic  = "prompt_off_topic"
+)
+
+// DefaultMaxPromptLength is the longest prompt, in characters, accepted by default
+const DefaultMaxPromptLength = 500
+
+// PolicyError is a refusal from the input policy. It never involves a model call.
+type PolicyError struct {
+	Code    string
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// IsPolicyError reports whether err is a refusal, and returns it
+func IsPolicyError(err error) (*PolicyError, bool) {
+	var pe *PolicyError
+	ok := errors.As(err, &pe)
+	return pe, ok
+}
+
+// Policy validates and cleans user text before it reaches a prompt
+type Policy struct {
+	MaxLength int
+}
+
+// DefaultPolicy returns the policy used when none is configured
+func DefaultPolicy() Policy {
+	return Policy{MaxLength: DefaultMaxPromptLength}
+}
+
+// Attempts to override, reveal or replace the system prompt
+var injectionPatterns = compileAll(
+	`\b(ignore|disregard|forget|override|bypass)\b.{0,40}\b(previous|prior|above|earlier|system|all|your|the)\b.{0,20}\b(instructions?|prompts?|rules?|directions?|guidelines?)\b`,
+	`\b(reveal|show|print|repeat|output|leak)\b.{0,30}\b(system prompt|instructions|your prompt|hidden prompt)\b`,
+	`\bsystem prompt\b`,
+	`\byou are (now|no longer)\b`,
+	`\b(pretend|act) (to be|as if|as an?)\b.{0,30}\b(ai|assistant|chatbot|model|bot)\b`,
+	`\b(jailbreak|developer mode|dan mode|do anything now)\b`,
+	`\bnew (instructions|rules|persona)\b`,
+	`</?\s*(system|assistant|user|instructions?)\s*>`,
+	`\[/?(inst|system)\]`,
+	"```",
+)
+
+// Requests for work that has nothing to do with picking a film
+var offTopicPatterns = compileAll(
+	`\b(write|generate|fix|debug|refactor)\b.{0,30}\b(code|script|program|function|sql|regex)\b`,
+	`\b(python|javascript|golang|java|c\+\+|typescript|html|css)\b.{0,30}\b(code|script|function|program|snippet)\b`,
+	`\b(write|draft|compose)\b.{0,20}\b(an? )?(essay|email|cover letter|resume|poem|homework|assignment)\b`,
+	`\b(solve|calculate|compute)\b.{0,30}\b(equation|integral|math|problem)\b`,
+	`\btranslate\b.{0,30}\b(into|to)\b`,
+	`\b(stock|crypto|bitcoin)\b.{0,20}\b(price|tip|advice|prediction)\b`,
+	`\b(recipe|medical advice|legal advice|diagnos(e|is))\b`,
+)
+
+// Words that put an otherwise suspicious request back on topic
+var filmWords = compileAll(
+	`\b(movies?|films?|cinema|watch(ing)?|director|directed|actor|actress|cast|screenplay|documentar(y|ies)|series|trilogy|sequel|remake|letterboxd|imdb|genre|anime|animated)\b`,
+)
+
+func compileAll(patterns ...string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = regexp.MustCompile(`(?i)` + p)
+	}
+	return res
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitize strips control and formatting characters and collapses whitespace
+func Sanitize(s string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError:
+			return -1
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			// Cf covers zero-width and bidi override characters used to hide instructions
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(cleaned), " ")
+}
+
+// Check sanitizes prompt and rejects it if it is too long, tries to subvert the system prompt
+// or asks for something other than a film. The cleaned prompt is returned on success.
+func (p Policy) Check(prompt string) (string, error) {
+	cleaned := Sanitize(prompt)
+	if cleaned == "" {
+		return "", &PolicyError{Code: CodePromptEmpty, Message: "prompt is empty"}
+	}
+
+	maxLength := p.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxPromptLength
+	}
+	if utf8.RuneCountInString(cleaned) > maxLength {
+		return "", &PolicyError{
+			Code:    CodePromptTooLong,
+			Message: fmt.Sprintf("prompt must be at most %d characters", maxLength),
+		}
+	}
+
+	if matchesAny(injectionPatterns, cleaned) {
+		return "", &PolicyError{
+			Code:    CodePromptInjection,
+			Message: "prompt looks like an attempt to change the assistant's instructions",
+		}
+	}
+
+	if matchesAny(offTopicPatterns, cleaned) && !matchesAny(filmWords, cleaned) {
+		return "", &PolicyError{
+			Code:    CodePromptOffTopic,
+			Message: "prompt must be a request for a film recommendation",
+		}
+	}
+
+	return cleaned, nil
+}
+
+// CheckOptions applies the policy to the user-supplied template variables in opts
+func (p Policy) CheckOptions(opts RecommendOptions) (RecommendOptions, error) {
+	if opts.Taste != "" {
+		taste, err := p.Check(opts.Taste)
+		if err != nil {
+			return opts, err
+		}
+		opts.Taste = taste
+	}
+
+	exclusions := make([]string, 0, len(opts.Exclusions))
+	for _, title := range opts.Exclusions {
+		title, err := p.Check(title)
+		if err != nil {
+			return opts, err
+		}
+		exclusions = append(exclusions, title)
+	}
+	opts.Exclusions = exclusions
+	return opts, nil
+}
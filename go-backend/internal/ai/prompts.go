@@ -0,0 +1,265 @@
+package ai
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DefaultMode is the prompt template used when a request doesn't name one
+const DefaultMode = "default"
+
+// DoubleFeatureMode pairs films, so it always asks for at least two
+const DoubleFeatureMode = "double-feature"
+
+// ErrUnknownMode is returned when a request asks for a prompt mode that has no template
+var ErrUnknownMode = errors.New("unknown recommendation mode")
+
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// PromptVars are the values available to prompt templates
+type PromptVars struct {
+	Taste      string
+	Exclusions []string
+	Candidates []string
+	Count      int
+}
+
+type promptTemplate struct {
+	version int
+	tmpl    *template.Template
+}
+
+// PromptStore holds the parsed system prompt templates, keyed by mode
+type PromptStore struct {
+	dir string
+
+	mu          sync.RWMutex
+	templates   map[string]promptTemplate
+	fingerprint string
+}
+
+var promptFuncs = template.FuncMap{
+	"atLeast": func(min, n int) int {
+		if n < min {
+			return min
+		}
+		return n
+	},
+}
+
+// LoadPrompts parses the prompt templates in dir, or the embedded defaults if dir is empty.
+//
+// Templates are named <mode>.v<version>.tmpl and the highest version of each mode wins.
+// Files starting with "_" are partials that every mode can reference.
+func LoadPrompts(dir string) (*PromptStore, error) {
+	s := &PromptStore{dir: dir}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *PromptStore) fsys() fs.FS {
+	if s.dir == "" {
+		sub, _ := fs.Sub(embeddedPrompts, "prompts")
+		return sub
+	}
+	return os.DirFS(s.dir)
+}
+
+// Reload re-parses every template. On error the previously loaded templates are kept.
+func (s *PromptStore) Reload() error {
+	fsys := s.fsys()
+	templates, fingerprint, err := parsePrompts(fsys)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.templates = templates
+	s.fingerprint = fingerprint
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch polls the template directory and reloads it whenever a file changes, until ctx is done.
+// It is a no-op for the embedded templates.
+func (s *PromptStore) Watch(ctx context.Context, interval time.Duration) {
+	if s.dir == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fingerprint, err := promptFingerprint(s.fsys())
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to check prompt templates", "dir", s.dir, "error", err)
+			continue
+		}
+
+		s.mu.RLock()
+		changed := fingerprint != s.fingerprint
+		s.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err := s.Reload(); err != nil {
+			slog.WarnContext(ctx, "Keeping previous prompt templates, reload failed", "dir", s.dir, "error", err)
+			// Remember the broken state so the same error isn't logged every tick
+			s.mu.Lock()
+			s.fingerprint = fingerprint
+			s.mu.Unlock()
+			continue
+		}
+		slog.InfoContext(ctx, "Reloaded prompt templates", "dir", s.dir, "modes", s.Modes())
+	}
+}
+
+// Modes returns the names of every loaded mode, sorted
+func (s *PromptStore) Modes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	modes := make([]string, 0, len(s.templates))
+	for mode := range s.templates {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// RecommendModes returns the modes Recommend accepts, every mode but MarathonMode
+func (s *PromptStore) RecommendModes() []string {
+	return slices.DeleteFunc(s.Modes(), func(mode string) bool { return mode == MarathonMode })
+}
+
+// Render executes the template for mode with vars, returning the system prompt and the template version
+func (s *PromptStore) Render(mode string, vars PromptVars) (string, int, error) {
+	if mode == "" {
+		mode = DefaultMode
+	}
+	if vars.Count < 1 {
+		vars.Count = 1
+	}
+
+	s.mu.RLock()
+	t, ok := s.templates[mode]
+	s.mu.RUnlock()
+	if !ok {
+		return "", 0, fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+	}
+
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, vars); err != nil {
+		return "", 0, fmt.Errorf("failed to render %s prompt v%d: %w", mode, t.version, err)
+	}
+	return b.String(), t.version, nil
+}
+
+// parsePrompts parses every mode template in fsys along with the shared partials
+func parsePrompts(fsys fs.FS) (map[string]promptTemplate, string, error) {
+	names, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return nil, "", err
+	}
+
+	fingerprint, err := promptFingerprint(fsys)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var partials []string
+	templates := make(map[string]promptTemplate)
+	files := make(map[string]string)
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "_") {
+			partials = append(partials, name)
+			continue
+		}
+
+		mode, version, ok := parsePromptName(name)
+		if !ok {
+			return nil, "", fmt.Errorf("prompt template %s is not named <mode>.v<version>.tmpl", name)
+		}
+		if current, exists := templates[mode]; exists && current.version > version {
+			continue
+		}
+		templates[mode] = promptTemplate{version: version}
+		files[mode] = name
+	}
+
+	if len(templates) == 0 {
+		return nil, "", fmt.Errorf("no prompt templates found")
+	}
+	if _, ok := templates[DefaultMode]; !ok {
+		return nil, "", fmt.Errorf("missing %q prompt template", DefaultMode)
+	}
+
+	for mode, t := range templates {
+		tmpl, err := template.New(files[mode]).Funcs(promptFuncs).Option("missingkey=error").
+			ParseFS(fsys, append([]string{files[mode]}, partials...)...)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse prompt template %s: %w", files[mode], err)
+		}
+		t.tmpl = tmpl
+		templates[mode] = t
+	}
+
+	return templates, fingerprint, nil
+}
+
+// parsePromptName splits "hidden-gem.v2.tmpl" into its mode and version
+func parsePromptName(name string) (string, int, bool) {
+	base := strings.TrimSuffix(path.Base(name), ".tmpl")
+	i := strings.LastIndex(base, ".v")
+	if i <= 0 {
+		return "", 0, false
+	}
+	version, err := strconv.Atoi(base[i+2:])
+	if err != nil || version < 1 {
+		return "", 0, false
+	}
+	return base[:i], version, true
+}
+
+// promptFingerprint summarises the names, sizes and modification times of the templates in fsys
+func promptFingerprint(fsys fs.FS) (string, error) {
+	names, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, name := range names {
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
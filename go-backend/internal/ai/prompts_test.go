@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePrompts writes files into dir. An edited file's modification time is moved on a second,
+// so reloads notice it whatever the filesystem's timestamp resolution.
+func writePrompts(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		modified := time.Now()
+		if info, err := os.Stat(path); err == nil {
+			modified = info.ModTime().Add(time.Second)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func render(t *testing.T, s *PromptStore, mode string, vars PromptVars) (string, int) {
+	t.Helper()
+	text, version, err := s.Render(mode, vars)
+	if err != nil {
+		t.Fatalf("Render(%q) = %v", mode, err)
+	}
+	return text, version
+}
+
+func TestPromptVersions(t *testing.T) {
+	dir := t.TempDir()
+	writePrompts(t, dir, map[string]string{
+		"default.v1.tmpl":     "old default",
+		"default.v2.tmpl":     "new default",
+		"hidden-gem.v3.tmpl":  "gem three",
+		"hidden-gem.v10.tmpl": "gem ten",
+	})
+	s, err := LoadPrompts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text, version := render(t, s, "", PromptVars{}); text != "new default" || version != 2 {
+		t.Errorf("default mode = %q v%d, want the v2 template", text, version)
+	}
+	if text, version := render(t, s, "hidden-gem", PromptVars{}); text != "gem ten" || version != 10 {
+		t.Errorf("hidden-gem = %q v%d, want v10 over v3", text, version)
+	}
+	if got := strings.Join(s.Modes(), ","); got != "default,hidden-gem" {
+		t.Errorf("Modes() = %s", got)
+	}
+	if _, _, err := s.Render("noir", PromptVars{}); !errors.Is(err, ErrUnknownMode) {
+		t.Errorf("Render(unknown) = %v, want ErrUnknownMode", err)
+	}
+}
+
+func TestPromptVars(t *testing.T) {
+	dir := t.TempDir()
+	writePrompts(t, dir, map[string]string{
+		"_partials.tmpl":  `{{define "taste"}}taste={{.Taste}}{{end}}{{define "skip"}}{{range .Exclusions}}[{{.}}]{{end}}{{end}}`,
+		"default.v1.tmpl": `{{template "taste" .}} {{template "skip" .}} count={{.Count}} min={{atLeast 2 .Count}}`,
+	})
+	s, err := LoadPrompts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range s.Modes() {
+		if strings.HasPrefix(mode, "_") {
+			t.Errorf("partial loaded as mode %q", mode)
+		}
+	}
+
+	text, _ := render(t, s, "default", PromptVars{Taste: "slow cinema", Exclusions: []string{"Heat", "Alien"}, Count: 3})
+	if want := "taste=slow cinema [Heat][Alien] count=3 min=3"; text != want {
+		t.Errorf("Render = %q, want %q", text, want)
+	}
+
+	// A count below one is asked for as one film
+	text, _ = render(t, s, "default", PromptVars{})
+	if want := "taste=  count=1 min=2"; text != want {
+		t.Errorf("Render(no vars) = %q, want %q", text, want)
+	}
+}
+
+func TestPromptReload(t *testing.T) {
+	dir := t.TempDir()
+	writePrompts(t, dir, map[string]string{"default.v1.tmpl": "first"})
+	s, err := LoadPrompts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePrompts(t, dir, map[string]string{"default.v1.tmpl": "second edit"})
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := render(t, s, "default", PromptVars{}); text != "second edit" {
+		t.Errorf("after Reload = %q, want the edited template", text)
+	}
+
+	// A broken edit is refused and the last good templates kept
+	writePrompts(t, dir, map[string]string{"default.v1.tmpl": "{{.Taste"})
+	if err := s.Reload(); err == nil {
+		t.Error("Reload accepted a broken template")
+	}
+	if text, _ := render(t, s, "default", PromptVars{}); text != "second edit" {
+		t.Errorf("after a failed Reload = %q, want the previous template", text)
+	}
+}
+
+func TestPromptWatch(t *testing.T) {
+	dir := t.TempDir()
+	writePrompts(t, dir, map[string]string{"default.v1.tmpl": "first"})
+	s, err := LoadPrompts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Watch(ctx, 10*time.Millisecond)
+
+	writePrompts(t, dir, map[string]string{
+		"default.v1.tmpl": "edited while watching",
+		"classic.v1.tmpl": "a new mode",
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		text, _, err := s.Render("classic", PromptVars{})
+		if err == nil && text == "a new mode" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Watch never picked up the new mode, Render = %q, %v", text, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if text, _ := render(t, s, "default", PromptVars{}); text != "edited while watching" {
+		t.Errorf("default after Watch = %q, want the edited template", text)
+	}
+}
+
+func TestLoadPromptsErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"no default mode": {"classic.v1.tmpl": "x"},
+		"unversioned":     {"default.v1.tmpl": "x", "classic.tmpl": "x"},
+		"version zero":    {"default.v0.tmpl": "x"},
+		"bad syntax":      {"default.v1.tmpl": "{{if}}"},
+		"empty":           {},
+	}
+	for name, files := range tests {
+		dir := t.TempDir()
+		writePrompts(t, dir, files)
+		if _, err := LoadPrompts(dir); err == nil {
+			t.Errorf("%s: LoadPrompts succeeded", name)
+		}
+	}
+}
+
+func TestEmbeddedPrompts(t *testing.T) {
+	s, err := LoadPrompts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := PromptVars{Taste: "Kubrick and Lynch", Exclusions: []string{"Eraserhead"}, Candidates: []string{"Heat"}, Count: 3}
+	for _, mode := range s.Modes() {
+		text, version := render(t, s, mode, vars)
+		if version < 1 || !strings.Contains(text, "JSON") {
+			t.Errorf("%s v%d doesn't ask for JSON", mode, version)
+		}
+		if mode != MarathonMode && (!strings.Contains(text, "Kubrick and Lynch") || !strings.Contains(text, "- Eraserhead")) {
+			t.Errorf("%s prompt leaves out the taste or exclusions:\n%s", mode, text)
+		}
+	}
+}
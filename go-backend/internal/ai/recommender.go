@@ -0,0 +1,315 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/metrics"
+	"go-backend/internal/tracing"
+
+	"github.com/google/generative-ai-go/genai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/option"
+)
+
+// ErrNotConfigured is returned when recommendations are requested without a Gemini API key
+var ErrNotConfigured = errors.New("GEMINI_API_KEY not set")
+
+// ErrInvalidOverride is returned when a per-request generation override is out of range
+var ErrInvalidOverride = errors.New("invalid generation override")
+
+// Config controls the model a Recommender talks to and its default generation parameters
+type Config struct {
+	APIKey          string
+	Model           string
+	Temperature     float32
+	TopP            float32
+	TopK            int32
+	MaxOutputTokens int32
+	MaxPromptLength int
+}
+
+// Validate checks the default generation parameters against the ranges the model accepts
+func (c Config) Validate() error {
+	defaults := Overrides{
+		Temperature:     &c.Temperature,
+		TopP:            &c.TopP,
+		TopK:            &c.TopK,
+		MaxOutputTokens: &c.MaxOutputTokens,
+	}
+	if err := defaults.Validate(maxOutputTokensLimit); err != nil {
+		return err
+	}
+	if c.MaxPromptLength < 1 {
+		return fmt.Errorf("max prompt length must be positive")
+	}
+	return nil
+}
+
+// maxOutputTokensLimit caps the configured output length; recommendations are a few hundred tokens
+const maxOutputTokensLimit = 8192
+
+// Overrides are per-request generation parameters. Nil fields keep the configured default.
+type Overrides struct {
+	Temperature     *float32
+	TopP            *float32
+	TopK            *int32
+	MaxOutputTokens *int32
+}
+
+// Validate checks every set field against the range the model accepts.
+// MaxOutputTokens may not exceed maxTokens.
+func (o Overrides) Validate(maxTokens int32) error {
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+		return fmt.Errorf("%w: temperature must be between 0 and 2", ErrInvalidOverride)
+	}
+	if o.TopP != nil && (*o.TopP < 0 || *o.TopP > 1) {
+		return fmt.Errorf("%w: top_p must be between 0 and 1", ErrInvalidOverride)
+	}
+	if o.TopK != nil && (*o.TopK < 1 || *o.TopK > 100) {
+		return fmt.Errorf("%w: top_k must be between 1 and 100", ErrInvalidOverride)
+	}
+	if o.MaxOutputTokens != nil && (*o.MaxOutputTokens < 1 || *o.MaxOutputTokens > maxTokens) {
+		return fmt.Errorf("%w: max_output_tokens must be between 1 and %d", ErrInvalidOverride, maxTokens)
+	}
+	return nil
+}
+
+// UsageTracker accounts for and caps spend. Before every model call Reserve holds an estimate
+// of its tokens, failing if it doesn't fit the budget. settle then gets what the call used, 0 and
+// 0 if it failed.
+type UsageTracker interface {
+	Reserve(ctx context.Context, estimate int64) (settle func(promptTokens, responseTokens int32), err error)
+}
+
+// Recommender is a long-lived, concurrency-safe Gemini client shared by every request
+type Recommender struct {
+	cfg     Config
+	prompts *PromptStore
+	client  *genai.Client
+	model   *genai.GenerativeModel
+	policy  Policy
+	usage   UsageTracker
+}
+
+// NewRecommender connects to Gemini once. The model configured here is never mutated afterwards;
+// requests with overrides work on a copy.
+func NewRecommender(ctx context.Context, cfg Config, prompts *PromptStore) (*Recommender, error) {
+	if cfg.APIKey == "" {
+		return nil, ErrNotConfigured
+	}
+	if prompts == nil {
+		return nil, fmt.Errorf("prompt store is required")
+	}
+
+	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.APIKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+
+	model := client.GenerativeModel(cfg.Model)
+	model.SetTemperature(cfg.Temperature)
+	model.SetTopP(cfg.TopP)
+	model.SetTopK(cfg.TopK)
+	model.SetMaxOutputTokens(cfg.MaxOutputTokens)
+
+	return &Recommender{
+		cfg:     cfg,
+		prompts: prompts,
+		client:  client,
+		model:   model,
+		policy:  Policy{MaxLength: cfg.MaxPromptLength},
+	}, nil
+}
+
+// TrackUsage reports every model call to t. It must be called before the recommender is shared.
+func (r *Recommender) TrackUsage(t UsageTracker) {
+	r.usage = t
+}
+
+// Close releases the underlying Gemini connection
+func (r *Recommender) Close() error {
+	return r.client.Close()
+}
+
+// Ping checks the API key is accepted and the model exists by fetching the model's metadata,
+// which costs no tokens
+func (r *Recommender) Ping(ctx context.Context) error {
+	if _, err := r.model.Info(ctx); err != nil {
+		return fmt.Errorf("Gemini model %s unavailable: %w", r.cfg.Model, err)
+	}
+	return nil
+}
+
+// Config returns the configuration the recommender was built with
+func (r *Recommender) Config() Config {
+	return r.cfg
+}
+
+// Prompts returns the templates the recommender renders system prompts from
+func (r *Recommender) Prompts() *PromptStore {
+	return r.prompts
+}
+
+// Recommend asks for opts.Count films, or however many the mode's template asks for
+func (r *Recommender) Recommend(ctx context.Context, prompt string, opts RecommendOptions) ([]MovieData, error) {
+	// Marathons have their own response shape, see Marathon
+	if opts.Mode == MarathonMode {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMode, opts.Mode)
+	}
+
+	// Refuse unsafe input before spending anything on the model
+	prompt, err := r.policy.Check(prompt)
+	if err != nil {
+		return nil, err
+	}
+	opts, err = r.policy.CheckOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get Gemini API response
+	responseText, err := r.generate(ctx, prompt, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AI recommendation: %w", err)
+	}
+
+	// Parse the JSON response
+	movies, err := parseGeminiResponse(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+
+	// The model sometimes answers with more films than it was asked for
+	if want := wantedFilms(opts); len(movies) > want {
+		movies = movies[:want]
+	}
+	return movies, nil
+}
+
+// wantedFilms is how many films the system prompt asks for
+func wantedFilms(opts RecommendOptions) int {
+	if opts.Mode == DoubleFeatureMode {
+		return max(opts.Count, 2)
+	}
+	return max(opts.Count, 1)
+}
+
+// modelFor returns the shared model, or a copy with the request's overrides applied
+func (r *Recommender) modelFor(o Overrides) (*genai.GenerativeModel, error) {
+	if o == (Overrides{}) {
+		return r.model, nil
+	}
+	if err := o.Validate(r.cfg.MaxOutputTokens); err != nil {
+		return nil, err
+	}
+
+	model := *r.model
+	if o.Temperature != nil {
+		model.SetTemperature(*o.Temperature)
+	}
+	if o.TopP != nil {
+		model.SetTopP(*o.TopP)
+	}
+	if o.TopK != nil {
+		model.SetTopK(*o.TopK)
+	}
+	if o.MaxOutputTokens != nil {
+		model.SetMaxOutputTokens(*o.MaxOutputTokens)
+	}
+	return &model, nil
+}
+
+// estimateTokens is the most a call can cost: its prompts, at roughly four characters a token,
+// plus the longest answer the model may give
+func (r *Recommender) estimateTokens(systemPrompt, prompt string, o Overrides) int64 {
+	maxOutput := r.cfg.MaxOutputTokens
+	if o.MaxOutputTokens != nil {
+		maxOutput = *o.MaxOutputTokens
+	}
+	return int64(len(systemPrompt)+len(prompt))/4 + int64(maxOutput)
+}
+
+// tracer starts a span per Gemini call, so slow answers show up in a request's trace
+var tracer = tracing.Tracer("go-backend/internal/ai")
+
+func (r *Recommender) generate(ctx context.Context, prompt string, opts RecommendOptions) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "gemini.generate", trace.WithAttributes(
+		attribute.String("gen_ai.system", "gemini"),
+		attribute.String("gen_ai.request.model", r.cfg.Model),
+		attribute.String("ai.mode", modeOrDefault(opts.Mode)),
+	))
+	defer tracing.End(span, &err)
+
+	// Render the system prompt for the requested mode
+	systemPrompt, version, err := r.prompts.Render(opts.Mode, PromptVars{
+		Taste:      opts.Taste,
+		Exclusions: opts.Exclusions,
+		Candidates: opts.Candidates,
+		Count:      opts.Count,
+	})
+	if err != nil {
+		return "", err
+	}
+	slog.DebugContext(ctx, "Rendered system prompt", "mode", modeOrDefault(opts.Mode), "version", version)
+
+	model, err := r.modelFor(opts.Overrides)
+	if err != nil {
+		return "", err
+	}
+
+	var promptTokens, responseTokens int32
+	if r.usage != nil {
+		settle, err := r.usage.Reserve(ctx, r.estimateTokens(systemPrompt, prompt, opts.Overrides))
+		if err != nil {
+			return "", err
+		}
+		defer func() { settle(promptTokens, responseTokens) }()
+	}
+
+	// Generate content
+	start := time.Now()
+	resp, err := model.GenerateContent(ctx, genai.Text(systemPrompt), genai.Text(fmt.Sprintf("User prompt: %q", prompt)))
+	if err != nil {
+		metrics.AIDuration.WithLabelValues(r.cfg.Model, "error").Observe(time.Since(start).Seconds())
+		return "", fmt.Errorf("Gemini API call failed: %w", err)
+	}
+	metrics.AIDuration.WithLabelValues(r.cfg.Model, "ok").Observe(time.Since(start).Seconds())
+
+	// Account for the tokens even if the answer turns out to be unusable
+	if resp.UsageMetadata != nil {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", int(resp.UsageMetadata.PromptTokenCount)),
+			attribute.Int("gen_ai.usage.output_tokens", int(resp.UsageMetadata.CandidatesTokenCount)),
+		)
+		metrics.AITokens.WithLabelValues(r.cfg.Model, "prompt").Add(float64(resp.UsageMetadata.PromptTokenCount))
+		metrics.AITokens.WithLabelValues(r.cfg.Model, "response").Add(float64(resp.UsageMetadata.CandidatesTokenCount))
+		promptTokens, responseTokens = resp.UsageMetadata.PromptTokenCount, resp.UsageMetadata.CandidatesTokenCount
+		slog.DebugContext(ctx, "Gemini usage", "model", r.cfg.Model,
+			"prompt_tokens", resp.UsageMetadata.PromptTokenCount, "response_tokens", resp.UsageMetadata.CandidatesTokenCount)
+	}
+
+	// Extract text from response
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("no response from Gemini API")
+	}
+
+	// Get the text from the first part
+	text := ""
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if textPart, ok := part.(genai.Text); ok {
+			text = string(textPart)
+			break
+		}
+	}
+
+	if text == "" {
+		return "", fmt.Errorf("empty response from Gemini API")
+	}
+
+	return text, nil
+}
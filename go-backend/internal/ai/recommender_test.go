@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRecommendModes(t *testing.T) {
+	prompts, err := LoadPrompts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := prompts.RecommendModes()
+	if slices.Contains(modes, MarathonMode) {
+		t.Errorf("RecommendModes() = %v, includes %s", modes, MarathonMode)
+	}
+	if !slices.Contains(modes, DefaultMode) || !slices.Contains(modes, DoubleFeatureMode) {
+		t.Errorf("RecommendModes() = %v, missing recommend modes", modes)
+	}
+	if !slices.Contains(prompts.Modes(), MarathonMode) {
+		t.Errorf("Modes() = %v, lost %s", prompts.Modes(), MarathonMode)
+	}
+}
+
+func TestWantedFilms(t *testing.T) {
+	tests := []struct {
+		opts RecommendOptions
+		want int
+	}{
+		{RecommendOptions{}, 1},
+		{RecommendOptions{Count: 3}, 3},
+		{RecommendOptions{Mode: "hidden-gem", Count: 5}, 5},
+		{RecommendOptions{Mode: DoubleFeatureMode}, 2},
+		{RecommendOptions{Mode: DoubleFeatureMode, Count: 1}, 2},
+		{RecommendOptions{Mode: DoubleFeatureMode, Count: 4}, 4},
+	}
+	for _, tt := range tests {
+		if got := wantedFilms(tt.opts); got != tt.want {
+			t.Errorf("wantedFilms(%+v) = %d, want %d", tt.opts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Package apierror is the JSON error body every endpoint responds with
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// RequestIDHeader carries the ID a request is logged under, echoed in error bodies
+const RequestIDHeader = "X-Request-ID"
+
+// Error is an API error. Code is stable for clients to branch on, Message is for people and
+// may change, Details holds anything structured such as the accepted values of a parameter.
+type Error struct {
+	Status    int    `json:"-"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// New returns an error responded to with status
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Newf is New with a formatted message
+func Newf(status int, code, format string, args ...any) *Error {
+	return New(status, code, fmt.Sprintf(format, args...))
+}
+
+// WithDetails returns a copy of e carrying details
+func (e *Error) WithDetails(details any) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Write responds with e as JSON, tagged with the request's ID
+func Write(w http.ResponseWriter, r *http.Request, e *Error) {
+	body := *e
+	if body.RequestID == "" {
+		body.RequestID = r.Header.Get(RequestIDHeader)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Errors shared by several endpoints
+var (
+	ErrNotFound         = New(http.StatusNotFound, "not_found", "No endpoint at this path, see / for the list")
+	ErrMethodNotAllowed = New(http.StatusMethodNotAllowed, "method_not_allowed", "Method not supported for this path")
+	ErrRateLimited      = New(http.StatusTooManyRequests, "rate_limited", "Too many requests, please try again later")
+	ErrInternal         = New(http.StatusInternalServerError, "internal_error", "Something went wrong, please try again")
+	ErrUnauthorized     = New(http.StatusUnauthorized, "unauthorized", "Unauthorized")
+	ErrUsernameRequired = New(http.StatusBadRequest, "username_required", "Username parameter is required")
+	ErrWatchlistFailed  = New(http.StatusInternalServerError, "watchlist_failed", "Failed to get watchlist")
+)
@@ -0,0 +1,91 @@
+// Package cache keeps values for a while so expensive work, such as scraping a watchlist, isn't
+// repeated. Values live in memory, per instance, or in Redis, shared by every instance.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key isn't cached or has expired
+var ErrMiss = errors.New("cache miss")
+
+// Cache holds values under string keys until their ttl runs out
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// GetJSON decodes the value cached under key into v
+func GetJSON(ctx context.Context, c Cache, key string, v any) error {
+	data, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// SetJSON caches v under key, encoded as JSON
+func SetJSON(ctx context.Context, c Cache, key string, v any, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, data, ttl)
+}
+
+// sweepInterval is how often expired entries are looked for
+const sweepInterval = time.Minute
+
+// Memory is a Cache in this process. Expired entries are dropped as they are read and by a
+// sweep every so often, so memory only grows with live entries.
+type Memory struct {
+	mu        sync.Mutex
+	entries   map[string]entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemory returns an empty Memory cache
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]entry), lastSweep: time.Now()}
+}
+
+// Get returns the value under key, or ErrMiss
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	if time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return nil, ErrMiss
+	}
+	return e.value, nil
+}
+
+// Set stores value under key for ttl
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	m.entries[key] = entry{value: value, expires: now.Add(ttl)}
+	return nil
+}
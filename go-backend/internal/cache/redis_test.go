@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	c := NewRedis(client, "test:")
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "watchlist:alice"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("Get before Set = %v, want ErrMiss", err)
+	}
+
+	if err := SetJSON(ctx, c, "watchlist:alice", []string{"heat-1995"}, 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var films []string
+	if err := GetJSON(ctx, c, "watchlist:alice", &films); err != nil || len(films) != 1 || films[0] != "heat-1995" {
+		t.Fatalf("GetJSON = %v, %v", films, err)
+	}
+
+	key := "test:cache:watchlist:alice"
+	if ttl := mr.TTL(key); ttl != 10*time.Minute {
+		t.Fatalf("TTL of %s = %s, want 10m", key, ttl)
+	}
+	mr.FastForward(10 * time.Minute)
+	if _, err := c.Get(ctx, "watchlist:alice"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("Get after the TTL = %v, want ErrMiss", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package clientip works out the address of the client behind a request. X-Forwarded-For is
+// only believed when it was added by a trusted proxy, since anyone can send the header.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver finds client addresses, trusting forwarding headers from its proxies. A nil Resolver
+// trusts no one.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// ParsePrefixes reads CIDRs such as "10.0.0.0/8", or single addresses
+func ParsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR", cidr)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR", cidr)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// New returns a Resolver trusting proxies in cidrs
+func New(cidrs []string) (*Resolver, error) {
+	trusted, err := ParsePrefixes(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{trusted: trusted}, nil
+}
+
+// IP returns the client's address. When the connection comes from a trusted proxy, the
+// X-Forwarded-For hops are read right to left and the first one that isn't a trusted proxy is
+// the client; hops further left were written by the client and could say anything.
+func (res *Resolver) IP(r *http.Request) string {
+	remote := remoteAddr(r.RemoteAddr)
+	if !remote.IsValid() {
+		return r.RemoteAddr
+	}
+	if !res.isTrusted(remote) {
+		return remote.String()
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A garbled hop can't be followed, the last proxy that wrote a good one is all we know
+			break
+		}
+		client = hop.Unmap()
+		if !res.isTrusted(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// FromTrustedProxy reports whether r came straight from a trusted proxy, so the X-Forwarded-*
+// headers on it were set by the proxy rather than the client
+func (res *Resolver) FromTrustedProxy(r *http.Request) bool {
+	remote := remoteAddr(r.RemoteAddr)
+	return remote.IsValid() && res.isTrusted(remote)
+}
+
+func (res *Resolver) isTrusted(addr netip.Addr) bool {
+	if res == nil {
+		return false
+	}
+	for _, prefix := range res.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteAddr parses http.Request.RemoteAddr, "ip:port", dropping the port so every connection
+// from one client shares an address
+func remoteAddr(s string) netip.Addr {
+	host, _, err := net.SplitHostPort(s)
+	if err != nil {
+		host = s
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
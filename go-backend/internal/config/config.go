@@ -0,0 +1,424 @@
+// Package config loads the server's settings into one struct, from defaults, an optional
+// YAML or TOML file, environment variables and command line flags, in that order of precedence
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is every setting the server and the CLI read
+type Config struct {
+	// Env is "production" or anything else for development, NODE_ENV
+	Env  string `yaml:"env" toml:"env"`
+	Port int    `yaml:"port" toml:"port"`
+	// PublicBaseURL is the origin used in share links, worked out from each request when empty
+	PublicBaseURL  string   `yaml:"public_base_url" toml:"public_base_url"`
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	// TrustedProxies are the CIDRs whose X-Forwarded-For is believed when working out client IPs
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	// AdminToken guards /admin endpoints, which are disabled when it is empty
+	AdminToken string `yaml:"admin_token" toml:"admin_token"`
+	StorePath  string `yaml:"store_path" toml:"store_path"`
+	// DailyTokenBudget caps AI tokens spent per day, 0 meaning no cap
+	DailyTokenBudget int64 `yaml:"daily_token_budget" toml:"daily_token_budget"`
+
+	Log       Log       `yaml:"log" toml:"log"`
+	Server    Server    `yaml:"server" toml:"server"`
+	Metrics   Metrics   `yaml:"metrics" toml:"metrics"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	State     State     `yaml:"state" toml:"state"`
+	Gemini    Gemini    `yaml:"gemini" toml:"gemini"`
+	Prompts   Prompts   `yaml:"prompts" toml:"prompts"`
+	Posters   Posters   `yaml:"posters" toml:"posters"`
+	TMDB      TMDB      `yaml:"tmdb" toml:"tmdb"`
+}
+
+// Log configures the process-wide logger
+type Log struct {
+	// Level is debug, info, warn or error. Per-film scraper lines are debug.
+	Level string `yaml:"level" toml:"level"`
+	// Format is text for terminals or json for log collectors
+	Format string `yaml:"format" toml:"format"`
+}
+
+// Server holds the HTTP server's timeouts
+type Server struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	// WriteTimeout bounds a whole response; streamed exports lift it for themselves
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownTimeout is how long in-flight requests may finish after SIGTERM before they are cancelled
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+// Metrics controls the Prometheus /metrics endpoint
+type Metrics struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Token, when set, must be sent as a bearer token to scrape /metrics
+	Token string `yaml:"token" toml:"token"`
+}
+
+// Tracing controls OpenTelemetry tracing
+type Tracing struct {
+	// Exporter is none, otlp (OTLP/HTTP, e.g. to Jaeger) or stdout
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// Endpoint is the OTLP/HTTP endpoint, OTEL_EXPORTER_OTLP_ENDPOINT or localhost when empty
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// SampleRatio is the fraction of requests traced, 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// RateLimit allows each client Requests requests per Window to most endpoints, with separate
+// budgets for the endpoints that cost AI tokens and the ones that scrape Letterboxd
+type RateLimit struct {
+	Enabled  bool          `yaml:"enabled" toml:"enabled"`
+	Requests int           `yaml:"requests" toml:"requests"`
+	Window   time.Duration `yaml:"window" toml:"window"`
+	// Recommend covers /recommend and /marathon
+	Recommend Budget `yaml:"recommend" toml:"recommend"`
+	// Watchlist covers the watchlist and export endpoints
+	Watchlist Budget `yaml:"watchlist" toml:"watchlist"`
+}
+
+// Budget is Requests requests per Window, per client
+type Budget struct {
+	Requests int           `yaml:"requests" toml:"requests"`
+	Window   time.Duration `yaml:"window" toml:"window"`
+}
+
+// State is where rate limit buckets and cached watchlists are kept. The memory backend keeps
+// them per instance; with several instances behind a load balancer, redis shares them. Picks,
+// film details and AI usage stay in each instance's store either way.
+type State struct {
+	// Backend is memory or redis
+	Backend string `yaml:"backend" toml:"backend"`
+	// RedisURL is e.g. redis://:password@localhost:6379/0, required by the redis backend
+	RedisURL string `yaml:"redis_url" toml:"redis_url"`
+	// KeyPrefix starts every Redis key, so instances of different deployments can share a server
+	KeyPrefix string `yaml:"key_prefix" toml:"key_prefix"`
+	// WatchlistTTL is how long scraped watchlists are reused, 0 to always scrape
+	WatchlistTTL time.Duration `yaml:"watchlist_ttl" toml:"watchlist_ttl"`
+}
+
+// StateBackends are the backends State accepts
+var StateBackends = []string{"memory", "redis"}
+
+// Gemini is the model recommendations are asked of and its default generation parameters
+type Gemini struct {
+	APIKey          string  `yaml:"api_key" toml:"api_key"`
+	Model           string  `yaml:"model" toml:"model"`
+	Temperature     float32 `yaml:"temperature" toml:"temperature"`
+	TopP            float32 `yaml:"top_p" toml:"top_p"`
+	TopK            int32   `yaml:"top_k" toml:"top_k"`
+	MaxOutputTokens int32   `yaml:"max_output_tokens" toml:"max_output_tokens"`
+}
+
+// Prompts configures the recommend prompt templates and the prompt policy
+type Prompts struct {
+	// Dir holds template overrides, watched for edits. The embedded templates are used when empty.
+	Dir       string `yaml:"dir" toml:"dir"`
+	MaxLength int    `yaml:"max_length" toml:"max_length"`
+}
+
+// Posters configures where posters are resolved from and where they are cached
+type Posters struct {
+	// Sources are tried in order, e.g. "letterboxd-ajax:5s,tmdb-id:3s"
+	Sources  string `yaml:"sources" toml:"sources"`
+	CacheDir string `yaml:"cache_dir" toml:"cache_dir"`
+}
+
+// TMDB holds the credentials for TMDB poster sources, which are skipped without a token
+type TMDB struct {
+	AccessToken string `yaml:"access_token" toml:"access_token"`
+	BaseURL     string `yaml:"base_url" toml:"base_url"`
+}
+
+// DataDir is where the store and poster cache live unless configured otherwise, relative to the
+// working directory. It has to outlive the process, so it is never a temporary directory.
+const DataDir = "data"
+
+// DefaultTrustedProxies is loopback only, a proxy on the same host. Operators behind a proxy
+// elsewhere, Docker's included, add its range.
+var DefaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+// Default returns the settings used when nothing overrides them
+func Default() *Config {
+	return &Config{
+		Port:           8081,
+		AllowedOrigins: []string{"http://localhost:5173", "http://localhost:3000"},
+		TrustedProxies: slices.Clone(DefaultTrustedProxies),
+		StorePath:      filepath.Join(DataDir, "store.db"),
+		Log:            Log{Level: "info", Format: "text"},
+		Server: Server{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      90 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Metrics: Metrics{Enabled: true},
+		Tracing: Tracing{Exporter: "none", SampleRatio: 1},
+		RateLimit: RateLimit{
+			Enabled:   true,
+			Requests:  100,
+			Window:    15 * time.Minute,
+			Recommend: Budget{Requests: 20, Window: 15 * time.Minute},
+			Watchlist: Budget{Requests: 60, Window: 15 * time.Minute},
+		},
+		State: State{Backend: "memory", KeyPrefix: "go-backend:", WatchlistTTL: 10 * time.Minute},
+		// The generation parameters the recommend endpoint has always used
+		Gemini: Gemini{
+			Model:           "gemini-1.5-flash",
+			Temperature:     0.7,
+			TopP:            0.8,
+			TopK:            40,
+			MaxOutputTokens: 1000,
+		},
+		Prompts: Prompts{MaxLength: 500},
+		Posters: Posters{
+			Sources:  "letterboxd-ajax:5s,letterboxd-og:5s,tmdb-id:3s,tmdb-images:3s,tmdb-search:3s",
+			CacheDir: filepath.Join(DataDir, "posters"),
+		},
+	}
+}
+
+// Check validates a setting that another package interprets, such as the log level, so config
+// itself only deals in data. Key names the setting in errors.
+type Check struct {
+	Key string
+	Run func(*Config) error
+}
+
+// Load builds the configuration from defaults, then the file named by -config or CONFIG_FILE,
+// then environment variables, then the flags in args, and validates it along with checks. It
+// returns the arguments left after the flags. With a nil fs no flags are read.
+func Load(fs *flag.FlagSet, args []string, checks ...Check) (*Config, []string, error) {
+	cfg := Default()
+
+	path := os.Getenv("CONFIG_FILE")
+	var configFlag string
+	if fs != nil {
+		fs.StringVar(&configFlag, "config", path, "YAML or TOML config file (CONFIG_FILE)")
+		for _, s := range cfg.settings() {
+			if s.flag != "" {
+				fs.Var(s.value, s.flag, s.usage+" ("+s.env+")")
+			}
+		}
+		// The file sits below the environment, so it is found before anything else is applied
+		if p, ok := lookupFlag(args, "config"); ok {
+			path = p
+		}
+	}
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, nil, err
+	}
+
+	var rest []string
+	if fs != nil {
+		if err := fs.Parse(args); err != nil {
+			return nil, nil, err
+		}
+		rest = fs.Args()
+	}
+
+	if err := cfg.Validate(checks...); err != nil {
+		return nil, nil, err
+	}
+	return cfg, rest, nil
+}
+
+// FromEnv loads the configuration without flags, for commands that have their own
+func FromEnv(checks ...Check) (*Config, error) {
+	cfg, _, err := Load(nil, nil, checks...)
+	return cfg, err
+}
+
+// lookupFlag finds -name value, -name=value or the two dash forms in args before flag parsing
+func lookupFlag(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if trimmed == arg {
+			continue
+		}
+		if v, ok := strings.CutPrefix(trimmed, name+"="); ok {
+			return v, true
+		}
+		if trimmed == name && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// loadFile reads path over the current settings, as YAML or TOML depending on its extension.
+// Keys the struct does not have are errors, so typos are not silently ignored.
+func (c *Config) loadFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		defer f.Close()
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.DecodeFile(path, c)
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("config file %s: extension must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// loadEnv applies every environment variable that is set, naming the variable in any error
+func (c *Config) loadEnv() error {
+	var errs []error
+	for _, s := range c.settings() {
+		v, ok := os.LookupEnv(s.env)
+		if !ok || v == "" {
+			continue
+		}
+		if err := s.value.Set(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks every setting, then runs checks, and reports all of the problems at once
+func (c *Config) Validate(checks ...Check) error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+
+	check(c.Port > 0 && c.Port < 65536, "port", "must be between 1 and 65535, got %d", c.Port)
+	if c.PublicBaseURL != "" {
+		check(strings.HasPrefix(c.PublicBaseURL, "http://") || strings.HasPrefix(c.PublicBaseURL, "https://"),
+			"public_base_url", "must start with http:// or https://, got %q", c.PublicBaseURL)
+	}
+	for _, origin := range c.AllowedOrigins {
+		check(origin != "*", "allowed_origins", "\"*\" cannot be used with credentials, list the origins")
+	}
+	check(c.StorePath != "", "store_path", "must not be empty")
+	check(c.DailyTokenBudget >= 0, "daily_token_budget", "must not be negative, got %d", c.DailyTokenBudget)
+
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	for _, t := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	} {
+		check(t.d > 0, t.key, "must be positive, got %s", t.d)
+	}
+
+	if c.RateLimit.Enabled {
+		for _, b := range []struct {
+			key string
+			Budget
+		}{
+			{"rate_limit", Budget{c.RateLimit.Requests, c.RateLimit.Window}},
+			{"rate_limit.recommend", c.RateLimit.Recommend},
+			{"rate_limit.watchlist", c.RateLimit.Watchlist},
+		} {
+			check(b.Requests > 0, b.key+".requests", "must be positive, got %d", b.Requests)
+			check(b.Window > 0, b.key+".window", "must be positive, got %s", b.Window)
+		}
+	}
+	check(slices.Contains(StateBackends, c.State.Backend), "state.backend", "must be memory or redis, got %q", c.State.Backend)
+	if c.State.Backend == "redis" {
+		check(c.State.RedisURL != "", "state.redis_url", "must be set for the redis backend")
+	}
+	if c.State.RedisURL != "" {
+		check(strings.HasPrefix(c.State.RedisURL, "redis://") || strings.HasPrefix(c.State.RedisURL, "rediss://") || strings.HasPrefix(c.State.RedisURL, "unix://"),
+			"state.redis_url", "must start with redis://, rediss:// or unix://")
+	}
+	check(c.State.WatchlistTTL >= 0, "state.watchlist_ttl", "must not be negative, got %s", c.State.WatchlistTTL)
+
+	check(c.Gemini.Model != "", "gemini.model", "must not be empty")
+	check(c.Prompts.MaxLength > 0, "prompts.max_length", "must be positive, got %d", c.Prompts.MaxLength)
+	check(c.Posters.CacheDir != "", "posters.cache_dir", "must not be empty")
+
+	for _, ch := range checks {
+		if err := ch.Run(c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Key, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Problems: errs}
+	}
+	return nil
+}
+
+// ValidationError lists every invalid setting, one per line
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// Production reports whether the server runs in production mode
+func (c *Config) Production() bool {
+	return c.Env == "production"
+}
+
+// Addr is the address the server listens on
+func (c *Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
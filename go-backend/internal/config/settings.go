@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting ties a field to its environment variable and flag. Secrets have no flag, so they
+// never show up in process listings.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value value
+}
+
+// value is a flag.Value that also parses environment variables
+type value interface {
+	String() string
+	Set(string) error
+}
+
+// settings lists every field that can be set from the environment or a flag
+func (c *Config) settings() []setting {
+	return []setting{
+		{"NODE_ENV", "env", "environment, production or development", (*stringValue)(&c.Env)},
+		{"PORT", "port", "port to listen on", (*intValue)(&c.Port)},
+		{"PUBLIC_BASE_URL", "public-base-url", "origin used in share links", (*stringValue)(&c.PublicBaseURL)},
+		{"ALLOWED_ORIGINS", "allowed-origins", "comma-separated CORS origins", (*listValue)(&c.AllowedOrigins)},
+		{"ADMIN_TOKEN", "", "", (*stringValue)(&c.AdminToken)},
+		{"STORE_PATH", "store", "path to the SQLite store", (*stringValue)(&c.StorePath)},
+		{"DAILY_TOKEN_BUDGET", "daily-token-budget", "AI tokens allowed per day, 0 for no cap", (*int64Value)(&c.DailyTokenBudget)},
+
+		{"LOG_LEVEL", "log-level", "debug, info, warn or error", (*stringValue)(&c.Log.Level)},
+		{"LOG_FORMAT", "log-format", "text or json", (*stringValue)(&c.Log.Format)},
+
+		{"SERVER_READ_HEADER_TIMEOUT", "read-header-timeout", "time allowed to read request headers", (*durationValue)(&c.Server.ReadHeaderTimeout)},
+		{"SERVER_READ_TIMEOUT", "read-timeout", "time allowed to read a whole request", (*durationValue)(&c.Server.ReadTimeout)},
+		{"SERVER_WRITE_TIMEOUT", "write-timeout", "time allowed to write a response", (*durationValue)(&c.Server.WriteTimeout)},
+		{"SERVER_IDLE_TIMEOUT", "idle-timeout", "how long keep-alive connections wait for the next request", (*durationValue)(&c.Server.IdleTimeout)},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long in-flight requests may finish on shutdown", (*durationValue)(&c.Server.ShutdownTimeout)},
+
+		{"METRICS_ENABLED", "metrics", "serve Prometheus metrics on /metrics", (*boolValue)(&c.Metrics.Enabled)},
+		{"METRICS_TOKEN", "", "", (*stringValue)(&c.Metrics.Token)},
+
+		{"TRACING_EXPORTER", "tracing", "trace exporter, none, otlp or stdout", (*stringValue)(&c.Tracing.Exporter)},
+		{"TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP endpoint, e.g. http://localhost:4318", (*stringValue)(&c.Tracing.Endpoint)},
+		{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of requests traced, 0 to 1", (*float64Value)(&c.Tracing.SampleRatio)},
+
+		{"ENABLE_RATE_LIMITING", "rate-limit", "limit requests per client", (*boolValue)(&c.RateLimit.Enabled)},
+		{"RATE_LIMIT_REQUESTS", "rate-limit-requests", "requests allowed per client per window", (*intValue)(&c.RateLimit.Requests)},
+		{"RATE_LIMIT_WINDOW", "rate-limit-window", "rate limit window, e.g. 15m", (*durationValue)(&c.RateLimit.Window)},
+		{"RATE_LIMIT_RECOMMEND_REQUESTS", "rate-limit-recommend-requests", "recommend and marathon requests allowed per client per window", (*intValue)(&c.RateLimit.Recommend.Requests)},
+		{"RATE_LIMIT_RECOMMEND_WINDOW", "rate-limit-recommend-window", "recommend and marathon rate limit window", (*durationValue)(&c.RateLimit.Recommend.Window)},
+		{"RATE_LIMIT_WATCHLIST_REQUESTS", "rate-limit-watchlist-requests", "watchlist requests allowed per client per window", (*intValue)(&c.RateLimit.Watchlist.Requests)},
+		{"RATE_LIMIT_WATCHLIST_WINDOW", "rate-limit-watchlist-window", "watchlist rate limit window", (*durationValue)(&c.RateLimit.Watchlist.Window)},
+		{"TRUSTED_PROXIES", "trusted-proxies", "comma-separated CIDRs whose X-Forwarded-For is trusted", (*listValue)(&c.TrustedProxies)},
+
+		{"STATE_BACKEND", "state-backend", "where rate limits and cached watchlists live, memory or redis", (*stringValue)(&c.State.Backend)},
+		{"REDIS_URL", "", "", (*stringValue)(&c.State.RedisURL)},
+		{"REDIS_KEY_PREFIX", "redis-key-prefix", "prefix of every Redis key", (*stringValue)(&c.State.KeyPrefix)},
+		{"WATCHLIST_CACHE_TTL", "watchlist-cache-ttl", "how long scraped watchlists are reused, 0 to always scrape", (*durationValue)(&c.State.WatchlistTTL)},
+
+		{"GEMINI_API_KEY", "", "", (*stringValue)(&c.Gemini.APIKey)},
+		{"GEMINI_MODEL", "gemini-model", "Gemini model name", (*stringValue)(&c.Gemini.Model)},
+		{"GEMINI_TEMPERATURE", "gemini-temperature", "default temperature, 0 to 2", (*float32Value)(&c.Gemini.Temperature)},
+		{"GEMINI_TOP_P", "gemini-top-p", "default top_p, 0 to 1", (*float32Value)(&c.Gemini.TopP)},
+		{"GEMINI_TOP_K", "gemini-top-k", "default top_k, 1 to 100", (*int32Value)(&c.Gemini.TopK)},
+		{"GEMINI_MAX_OUTPUT_TOKENS", "gemini-max-output-tokens", "default max output tokens", (*int32Value)(&c.Gemini.MaxOutputTokens)},
+
+		{"PROMPT_TEMPLATE_DIR", "prompt-dir", "directory of prompt template overrides", (*stringValue)(&c.Prompts.Dir)},
+		{"PROMPT_MAX_LENGTH", "prompt-max-length", "longest prompt accepted, in characters", (*intValue)(&c.Prompts.MaxLength)},
+
+		{"POSTER_SOURCES", "poster-sources", "poster sources in order, e.g. letterboxd-ajax:5s,tmdb-id:3s", (*stringValue)(&c.Posters.Sources)},
+		{"POSTER_CACHE_DIR", "poster-cache-dir", "directory posters are cached in", (*stringValue)(&c.Posters.CacheDir)},
+
+		{"TMDB_ACCESS_TOKEN", "", "", (*stringValue)(&c.TMDB.AccessToken)},
+		{"TMDB_BASE_URL", "tmdb-base-url", "TMDB API root", (*stringValue)(&c.TMDB.BaseURL)},
+	}
+}
+
+type stringValue string
+
+func (v *stringValue) String() string     { return string(*v) }
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+type int32Value int32
+
+func (v *int32Value) String() string { return strconv.FormatInt(int64(*v), 10) }
+func (v *int32Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v = int32Value(n)
+	return nil
+}
+
+type int64Value int64
+
+func (v *int64Value) String() string { return strconv.FormatInt(int64(*v), 10) }
+func (v *int64Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v = int64Value(n)
+	return nil
+}
+
+type float32Value float32
+
+func (v *float32Value) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 32) }
+func (v *float32Value) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*v = float32Value(f)
+	return nil
+}
+
+type float64Value float64
+
+func (v *float64Value) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+func (v *float64Value) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*v = float64Value(f)
+	return nil
+}
+
+type boolValue bool
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+// IsBoolFlag lets -rate-limit be given without a value
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+type durationValue time.Duration
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration, e.g. 15m", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+// listValue is a comma-separated list, with blanks dropped
+type listValue []string
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+func (v *listValue) Set(s string) error {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v = items
+	return nil
+}
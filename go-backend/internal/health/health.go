@@ -0,0 +1,112 @@
+// Package health runs the dependency checks behind the readiness endpoint
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check is one dependency. Critical checks failing make the server unready; the others only
+// mark it degraded, since every instance shares them and pulling all of them out of rotation
+// would not help.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// Statuses of a check and of a whole report
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check. Status is fail if a critical check failed, degraded
+// if any other did, ok otherwise.
+type Report struct {
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+// Checker runs checks in parallel, each under timeout, and reuses a report for ttl so frequent
+// probes don't hammer Letterboxd or the model provider
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu   sync.Mutex
+	last *Report
+}
+
+// NewChecker returns a Checker for checks
+func NewChecker(timeout, ttl time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout, ttl: ttl}
+}
+
+// Report returns the latest report, running the checks if it is older than the ttl
+func (c *Checker) Report(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil && time.Since(c.last.CheckedAt) < c.ttl {
+		return *c.last
+	}
+
+	// The report is shared, so one caller hanging up mustn't fail it for everyone
+	ctx = context.WithoutCancel(ctx)
+	report := Report{Status: StatusOK, CheckedAt: time.Now().UTC(), Checks: make([]Result, len(c.checks))}
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status == StatusOK {
+			continue
+		}
+		if r.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	c.last = &report
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
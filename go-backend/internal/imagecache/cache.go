@@ -0,0 +1,405 @@
+package imagecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-backend/internal/metrics"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound is returned when no source has an image for a key
+var ErrNotFound = errors.New("image not found")
+
+//go:embed placeholder.png
+var placeholderPNG []byte
+
+// Widths are the sizes images can be resized to. Requests are snapped up to the next one
+// so the cache holds a handful of variants per image rather than one per pixel width.
+var Widths = []int{150, 230, 300, 500, 780, 1000}
+
+// fetchTimeout bounds resolving and downloading one image, however many requests wait on it
+const fetchTimeout = 30 * time.Second
+
+// maxImageBytes stops a misbehaving upstream from filling the disk
+const maxImageBytes = 10 << 20
+
+// maxImagePixels is the most pixels an image may decode to. A small file can still claim huge
+// dimensions, so sizes are checked before anything is decoded.
+const maxImagePixels = 4000 * 6000
+
+// ImageHosts are the only hosts images are downloaded from, Letterboxd's and TMDB's CDNs. Source
+// URLs can come from scraped pages and the model, so anything else is refused.
+var ImageHosts = []string{"a.ltrbxd.com", "image.tmdb.org"}
+
+// Image is an image ready to be served
+type Image struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+	Placeholder bool
+}
+
+// entry records what a key resolved to. A missing entry has no hash and is retried after missTTL,
+// a found one is fetched again after maxAge.
+type entry struct {
+	Hash        string    `json:"hash,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	SourceURL   string    `json:"source_url,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Blurhash    string    `json:"blurhash,omitempty"`
+	Palette     []string  `json:"palette,omitempty"`
+}
+
+// ResolveFunc finds the upstream URL of the image for a key
+type ResolveFunc func(ctx context.Context) (string, error)
+
+// Cache stores images on disk by the SHA-256 of their contents, with an index from keys to hashes
+type Cache struct {
+	dir     string
+	client  *http.Client
+	missTTL time.Duration
+	maxAge  time.Duration
+
+	group        singleflight.Group
+	placeholders sync.Map // width -> *Image
+}
+
+// New opens (creating if needed) a cache rooted at dir
+func New(dir string, client *http.Client) (*Cache, error) {
+	for _, sub := range []string{"objects", "index"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create image cache: %w", err)
+		}
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	// A copy, so refusing redirects off the allowed hosts doesn't change the caller's client
+	cp := *client
+	cp.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return checkSource(req.URL)
+	}
+	return &Cache{dir: dir, client: &cp, missTTL: time.Hour, maxAge: 30 * 24 * time.Hour}, nil
+}
+
+// checkSource refuses URLs that aren't https on one of ImageHosts
+func checkSource(u *url.URL) error {
+	if u.Scheme != "https" || !slices.Contains(ImageHosts, u.Hostname()) || u.Port() != "" {
+		return fmt.Errorf("images are not fetched from %s", u.Redacted())
+	}
+	return nil
+}
+
+// SnapWidth returns the smallest supported width at least w, 0 for the original size
+func SnapWidth(w int) int {
+	if w <= 0 {
+		return 0
+	}
+	for _, width := range Widths {
+		if width >= w {
+			return width
+		}
+	}
+	return Widths[len(Widths)-1]
+}
+
+// Get returns the image for key at width (0 for the original), fetching it with resolve on first use
+func (c *Cache) Get(ctx context.Context, key string, width int, resolve ResolveFunc) (*Image, error) {
+	e, err := c.entry(ctx, key, resolve)
+	if err != nil {
+		return nil, err
+	}
+	if e.Hash == "" {
+		return nil, ErrNotFound
+	}
+
+	width = SnapWidth(width)
+	if width == 0 {
+		data, err := os.ReadFile(c.objectPath(e.Hash, ""))
+		if err != nil {
+			return nil, err
+		}
+		return &Image{Data: data, ContentType: e.ContentType, ETag: `"` + e.Hash + `"`}, nil
+	}
+
+	variant := fmt.Sprintf("w%d", width)
+	if data, err := os.ReadFile(c.objectPath(e.Hash, variant)); err == nil {
+		return &Image{Data: data, ContentType: "image/jpeg", ETag: `"` + e.Hash + "-" + variant + `"`}, nil
+	}
+
+	original, err := os.ReadFile(c.objectPath(e.Hash, ""))
+	if err != nil {
+		return nil, err
+	}
+	data, err := resize(original, width)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAtomic(c.objectPath(e.Hash, variant), data); err != nil {
+		slog.WarnContext(ctx, "Failed to cache poster variant", "key", key, "variant", variant, "error", err)
+	}
+	return &Image{Data: data, ContentType: "image/jpeg", ETag: `"` + e.Hash + "-" + variant + `"`}, nil
+}
+
+// Placeholder returns the bundled "no image" poster at width (0 for the original size)
+func (c *Cache) Placeholder(width int) *Image {
+	width = SnapWidth(width)
+	if img, ok := c.placeholders.Load(width); ok {
+		return img.(*Image)
+	}
+
+	img := &Image{Data: placeholderPNG, ContentType: "image/png", ETag: `"placeholder"`, Placeholder: true}
+	if width > 0 {
+		if data, err := resize(placeholderPNG, width); err == nil {
+			img = &Image{Data: data, ContentType: "image/jpeg", ETag: fmt.Sprintf(`"placeholder-w%d"`, width), Placeholder: true}
+		}
+	}
+	c.placeholders.Store(width, img)
+	return img
+}
+
+// entry reads the index for key, fetching the image once (per key, across concurrent requests)
+// if it has never been seen or has expired. An expired image is still served if the upstream
+// can't be reached.
+func (c *Cache) entry(ctx context.Context, key string, resolve ResolveFunc) (*entry, error) {
+	cached, err := c.readIndex(key)
+	if err != nil {
+		cached = nil
+	}
+	if cached != nil {
+		ttl := c.maxAge
+		if cached.Hash == "" {
+			ttl = c.missTTL
+		}
+		if time.Since(cached.FetchedAt) < ttl {
+			metrics.CacheLookup("poster", true)
+			return cached, nil
+		}
+	}
+	metrics.CacheLookup("poster", false)
+
+	// The fetch is shared by every request waiting on key, so it mustn't stop when the one that
+	// started it goes away; each caller stops waiting when its own context ends instead
+	ch := c.group.DoChan(key, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+		return c.fetch(fetchCtx, key, resolve)
+	})
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-ch:
+	}
+	if res.Err != nil {
+		if cached != nil && cached.Hash != "" {
+			slog.WarnContext(ctx, "Failed to refresh image, serving the cached one", "key", key, "error", res.Err)
+			return cached, nil
+		}
+		return nil, res.Err
+	}
+	return res.Val.(*entry), nil
+}
+
+func (c *Cache) fetch(ctx context.Context, key string, resolve ResolveFunc) (*entry, error) {
+	e := &entry{FetchedAt: time.Now().UTC()}
+
+	sourceURL, err := resolve(ctx)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// Running out of time says nothing about whether the image exists
+		return nil, ctxErr
+	}
+	if err != nil || sourceURL == "" {
+		slog.DebugContext(ctx, "No image, caching the miss", "key", key, "error", err)
+		return e, c.writeIndex(key, e)
+	}
+
+	data, contentType, err := c.download(ctx, sourceURL)
+	if err != nil {
+		// Upstream trouble isn't a miss, try again on the next request
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	e.Hash = hex.EncodeToString(sum[:])
+	e.ContentType = contentType
+	e.SourceURL = sourceURL
+	if preview, err := computePreview(data); err == nil {
+		e.Blurhash = preview.Blurhash
+		e.Palette = preview.Palette
+	} else {
+		slog.WarnContext(ctx, "Failed to compute poster preview", "key", key, "error", err)
+	}
+
+	if err := writeAtomic(c.objectPath(e.Hash, ""), data); err != nil {
+		return nil, err
+	}
+	if err := c.writeIndex(key, e); err != nil {
+		return nil, err
+	}
+	slog.DebugContext(ctx, "Cached image", "key", key, "source", sourceURL, "bytes", len(data))
+	return e, nil
+}
+
+func (c *Cache) download(ctx context.Context, sourceURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkSource(req.URL); err != nil {
+		return nil, "", err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch %s: %w", sourceURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to fetch %s: status %d", sourceURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", sourceURL, err)
+	}
+	if len(data) > maxImageBytes {
+		return nil, "", fmt.Errorf("image at %s is larger than %d bytes", sourceURL, maxImageBytes)
+	}
+
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("%s is not an image (%s)", sourceURL, contentType)
+	}
+	if err := checkSize(data); err != nil {
+		return nil, "", fmt.Errorf("image at %s: %w", sourceURL, err)
+	}
+	return data, contentType, nil
+}
+
+// checkSize reads an image's dimensions from its header and refuses ones too big to decode
+func checkSize(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to read image size: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return fmt.Errorf("image is %dx%d, larger than %d pixels", cfg.Width, cfg.Height, maxImagePixels)
+	}
+	return nil
+}
+
+// decode decodes an image after checking its size
+func decode(data []byte) (image.Image, error) {
+	if err := checkSize(data); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return src, nil
+}
+
+// objectPath shards objects by the first two hex digits of their hash
+func (c *Cache) objectPath(hash, variant string) string {
+	name := hash
+	if variant != "" {
+		name += "-" + variant + ".jpg"
+	}
+	return filepath.Join(c.dir, "objects", hash[:2], name)
+}
+
+func (c *Cache) indexPath(key string) string {
+	return filepath.Join(c.dir, "index", key+".json")
+}
+
+func (c *Cache) readIndex(key string) (*entry, error) {
+	data, err := os.ReadFile(c.indexPath(key))
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (c *Cache) writeIndex(key string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(c.indexPath(key), data)
+}
+
+// writeAtomic writes via a temp file and rename so readers never see a partial file
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// resize scales an encoded image down to width, keeping its aspect ratio, and encodes it as JPEG.
+// Images already narrower than width are re-encoded at their own size.
+func resize(data []byte, width int) ([]byte, error) {
+	src, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	if b.Dx() > width {
+		height := b.Dy() * width / b.Dx()
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+		src = dst
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,176 @@
+package imagecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckSource(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://a.ltrbxd.com/resized/film-poster/5/1/5/2/8/51528-pierrot-le-fou-0-500-0-750-crop.jpg", true},
+		{"https://image.tmdb.org/t/p/w500/abc.jpg", true},
+		{"http://a.ltrbxd.com/x.jpg", false},
+		{"https://a.ltrbxd.com:8443/x.jpg", false},
+		{"https://attacker.example/film/x/poster.jpg", false},
+		{"https://a.ltrbxd.com.attacker.example/x.jpg", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkSource(u); (err == nil) != tt.ok {
+			t.Errorf("checkSource(%s) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeRefusesHugeImages(t *testing.T) {
+	if _, err := decode(encodePNG(t, 20, 30)); err != nil {
+		t.Fatalf("decode small image: %v", err)
+	}
+
+	// A few kilobytes that would decode to 25 million pixels
+	bomb := encodePNG(t, 5000, 5000)
+	if len(bomb) > maxImageBytes {
+		t.Fatalf("bomb is %d bytes, expected it to fit under the download limit", len(bomb))
+	}
+	if _, err := decode(bomb); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("decode(5000x5000) = %v, want a size error", err)
+	}
+	if _, err := resize(bomb, 150); err == nil {
+		t.Fatal("resize accepted a 5000x5000 image")
+	}
+	if _, err := computePreview(bomb); err == nil {
+		t.Fatal("computePreview accepted a 5000x5000 image")
+	}
+}
+
+func TestGetRefusesOtherHosts(t *testing.T) {
+	c, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Get(context.Background(), "pierrot-le-fou", 0, func(context.Context) (string, error) {
+		return "https://attacker.example/film/pierrot-le-fou/poster.jpg", nil
+	})
+	if err == nil {
+		t.Fatal("Get downloaded an image from a host off the allow-list")
+	}
+	if _, err := c.readIndex("pierrot-le-fou"); err == nil {
+		t.Fatal("refused image was written to the index")
+	}
+}
+
+func TestEntryExpiry(t *testing.T) {
+	c, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(e entry) {
+		data, _ := json.Marshal(e)
+		if err := writeAtomic(c.indexPath("heat-1995"), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolved := 0
+	resolve := func(context.Context) (string, error) {
+		resolved++
+		return "", nil
+	}
+
+	// Fresh entries are served as they are
+	write(entry{Hash: strings.Repeat("ab", 32), SourceURL: "https://a.ltrbxd.com/x.jpg", FetchedAt: time.Now()})
+	if e, err := c.entry(context.Background(), "heat-1995", resolve); err != nil || e.Hash == "" {
+		t.Fatalf("entry = %+v, %v, want the cached image", e, err)
+	}
+	if resolved != 0 {
+		t.Fatalf("fresh entry was fetched again")
+	}
+
+	// Expired entries are fetched again
+	write(entry{Hash: strings.Repeat("ab", 32), SourceURL: "https://a.ltrbxd.com/x.jpg", FetchedAt: time.Now().Add(-c.maxAge - time.Hour)})
+	if _, err := c.entry(context.Background(), "heat-1995", resolve); err != nil {
+		t.Fatal(err)
+	}
+	if resolved != 1 {
+		t.Fatalf("expired entry was not fetched again")
+	}
+}
+
+func TestSharedFetchOutlivesCaller(t *testing.T) {
+	c, err := New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetchErr := make(chan error, 1)
+	var once sync.Once
+	resolve := func(ctx context.Context) (string, error) {
+		once.Do(func() { close(started) })
+		<-release
+		fetchErr <- ctx.Err()
+		return "", nil
+	}
+
+	// The first caller starts the fetch and gives up while it is running
+	first, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := c.entry(first, "heat-1995", resolve)
+		firstDone <- err
+	}()
+	<-started
+
+	secondDone := make(chan error, 1)
+	go func() {
+		e, err := c.entry(context.Background(), "heat-1995", resolve)
+		if err == nil && e.Hash != "" {
+			err = errors.New("expected a cached miss")
+		}
+		secondDone <- err
+	}()
+
+	cancel()
+	select {
+	case err := <-firstDone:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("cancelled caller got %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled caller kept waiting on the shared fetch")
+	}
+
+	close(release)
+	if err := <-fetchErr; err != nil {
+		t.Fatalf("shared fetch saw the first caller's cancellation: %v", err)
+	}
+	if err := <-secondDone; err != nil {
+		t.Fatalf("waiting caller got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package imagecache
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Blurhash components for portrait posters, more rows than columns
+const (
+	blurhashX = 3
+	blurhashY = 4
+)
+
+// paletteSize is how many dominant colors are extracted per poster
+const paletteSize = 4
+
+// Preview is what a client needs to draw a poster before the image itself has loaded
+type Preview struct {
+	Blurhash string   `json:"blurhash"`
+	Palette  []string `json:"palette"`
+}
+
+// Preview returns the blurhash and dominant colors for key, fetching the image with resolve on
+// first use. Previews are computed once and stored in the index next to the image hash.
+func (c *Cache) Preview(ctx context.Context, key string, resolve ResolveFunc) (*Preview, error) {
+	e, err := c.entry(ctx, key, resolve)
+	if err != nil {
+		return nil, err
+	}
+	if e.Hash == "" {
+		return nil, ErrNotFound
+	}
+	if e.Blurhash == "" {
+		// The image was cached but couldn't be decoded for a preview
+		return nil, ErrNotFound
+	}
+	return &Preview{Blurhash: e.Blurhash, Palette: e.Palette}, nil
+}
+
+// computePreview decodes an image and works out its blurhash and palette from a small thumbnail
+func computePreview(data []byte) (*Preview, error) {
+	src, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both only need a rough idea of the image, so work on a thumbnail
+	b := src.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+	width := min(b.Dx(), 48)
+	height := max(b.Dy()*width/b.Dx(), 1)
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(thumb, thumb.Bounds(), src, b, draw.Src, nil)
+
+	return &Preview{
+		Blurhash: encodeBlurhash(thumb, blurhashX, blurhashY),
+		Palette:  dominantColors(thumb, paletteSize),
+	}, nil
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func encode83(value, length int) string {
+	var b strings.Builder
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		b.WriteByte(base83Chars[digit])
+	}
+	return b.String()
+}
+
+func srgbToLinear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+// encodeBlurhash implements the reference blurhash encoder (https://blurha.sh)
+func encodeBlurhash(img *image.RGBA, xComponents, yComponents int) string {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var r, g, bl float64
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(h))
+					p := img.RGBAAt(b.Min.X+x, b.Min.Y+y)
+					r += basis * srgbToLinear(p.R)
+					g += basis * srgbToLinear(p.G)
+					bl += basis * srgbToLinear(p.B)
+				}
+			}
+
+			scale := normalisation / float64(w*h)
+			factors = append(factors, [3]float64{r * scale, g * scale, bl * scale})
+		}
+	}
+
+	dc, ac := factors[0], factors[1:]
+
+	var hash strings.Builder
+	hash.WriteString(encode83((xComponents-1)+(yComponents-1)*9, 1))
+
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = math.Max(actualMax, math.Max(math.Abs(f[0]), math.Max(math.Abs(f[1]), math.Abs(f[2]))))
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maximumValue = float64(quantisedMax+1) / 166
+		hash.WriteString(encode83(quantisedMax, 1))
+	} else {
+		hash.WriteString(encode83(0, 1))
+	}
+
+	hash.WriteString(encode83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+
+	quant := func(v float64) int {
+		return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maximumValue, 0.5)*9+9.5))))
+	}
+	for _, f := range ac {
+		hash.WriteString(encode83(quant(f[0])*19*19+quant(f[1])*19+quant(f[2]), 2))
+	}
+	return hash.String()
+}
+
+// dominantColors buckets pixels into a 4-bit-per-channel histogram and returns the most common
+// colors as hex strings, skipping colors too close to one already picked
+func dominantColors(img *image.RGBA, n int) []string {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := make(map[int]*bucket)
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p := img.RGBAAt(x, y)
+			key := int(p.R>>4)<<8 | int(p.G>>4)<<4 | int(p.B>>4)
+			bk, ok := buckets[key]
+			if !ok {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.count++
+			bk.r += int(p.R)
+			bk.g += int(p.G)
+			bk.b += int(p.B)
+		}
+	}
+
+	sorted := make([]*bucket, 0, len(buckets))
+	for _, bk := range buckets {
+		sorted = append(sorted, bk)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].count > sorted[j].count })
+
+	var picked [][3]int
+	for _, bk := range sorted {
+		c := [3]int{bk.r / bk.count, bk.g / bk.count, bk.b / bk.count}
+
+		distinct := true
+		for _, p := range picked {
+			dr, dg, db := c[0]-p[0], c[1]-p[1], c[2]-p[2]
+			if dr*dr+dg*dg+db*db < 48*48 {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			picked = append(picked, c)
+			if len(picked) == n {
+				break
+			}
+		}
+	}
+
+	palette := make([]string, len(picked))
+	for i, c := range picked {
+		palette[i] = fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+	}
+	return palette
+}
@@ -0,0 +1,73 @@
+package imagecache
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// fill returns a 6x8 image colored column by column with at
+func fill(at func(x int) color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 6, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 6; x++ {
+			img.SetRGBA(x, y, at(x))
+		}
+	}
+	return img
+}
+
+var (
+	red     = color.RGBA{255, 0, 0, 255}
+	nearRed = color.RGBA{240, 16, 16, 255}
+	blue    = color.RGBA{0, 0, 255, 255}
+)
+
+// Four red columns, one almost red and one blue
+func redBlue(x int) color.RGBA {
+	switch {
+	case x < 4:
+		return red
+	case x == 4:
+		return nearRed
+	}
+	return blue
+}
+
+func TestEncodeBlurhash(t *testing.T) {
+	// Expected hashes were worked out with the reference TypeScript encoder's algorithm
+	// (https://github.com/woltapp/blurhash) over the same pixels with 3x4 components. The
+	// reference basis has no half-pixel offset, so even a solid image has AC components.
+	tests := []struct {
+		name string
+		img  *image.RGBA
+		want string
+	}{
+		{"solid red", fill(func(int) color.RGBA { return red }), "TsTI:j|cfQ]9sUfQfQfQfQ]9sUfQ"},
+		{"red and blue", fill(redBlue), "T~QwvF|VJq,bwvWrfQfQfQ,bwvWr"},
+	}
+	for _, tt := range tests {
+		got := encodeBlurhash(tt.img, blurhashX, blurhashY)
+		if got != tt.want {
+			t.Errorf("%s: encodeBlurhash = %q, want %q", tt.name, got, tt.want)
+		}
+		// One character of size, one of maximum AC, four of DC and two per AC component
+		if want := 6 + 2*(blurhashX*blurhashY-1); len(got) != want {
+			t.Errorf("%s: hash is %d characters, want %d", tt.name, len(got), want)
+		}
+	}
+}
+
+func TestDominantColors(t *testing.T) {
+	// The almost red column is folded into red, leaving two colors even though four were asked for
+	got := dominantColors(fill(redBlue), paletteSize)
+	if want := []string{"#ff0000", "#0000ff"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dominantColors = %v, want %v", got, want)
+	}
+
+	got = dominantColors(fill(func(int) color.RGBA { return blue }), paletteSize)
+	if want := []string{"#0000ff"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dominantColors(solid blue) = %v, want %v", got, want)
+	}
+}
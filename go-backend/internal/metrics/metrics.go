@@ -0,0 +1,130 @@
+// Package metrics holds the Prometheus collectors the server exports on /metrics. The other
+// packages record into them directly; label values are kept to small fixed sets (route
+// patterns, source names, status codes) so the series count stays bounded.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every collector below plus the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	// HTTPRequests counts responses by route pattern, method and status code
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by route pattern, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	// HTTPDuration is how long requests took to serve, by route pattern and method
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to serve HTTP requests, by route pattern and method.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"route", "method"})
+
+	// ScraperPages counts pages fetched from Letterboxd, by collector
+	ScraperPages = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_pages_fetched_total",
+		Help: "Pages fetched from Letterboxd, by collector: watchlist, film, poster or ajax.",
+	}, []string{"collector"})
+
+	// LetterboxdResponses counts Letterboxd responses by status code, "error" when none came back
+	LetterboxdResponses = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "letterboxd_responses_total",
+		Help: "Responses from Letterboxd by status code, \"error\" for requests that got no response.",
+	}, []string{"code"})
+
+	// PosterResolutions counts poster source attempts by source and result: hit, miss or error
+	PosterResolutions = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "poster_resolutions_total",
+		Help: "Poster source attempts by source and result: hit, miss (placeholder) or error.",
+	}, []string{"source", "result"})
+
+	// AIDuration is how long Gemini calls took, by model and outcome: ok or error
+	AIDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ai_request_duration_seconds",
+		Help:    "Time taken by Gemini calls, by model and outcome: ok or error.",
+		Buckets: []float64{.25, .5, 1, 2, 4, 8, 15, 30, 60},
+	}, []string{"model", "outcome"})
+
+	// AITokens counts Gemini tokens by model and kind: prompt or response
+	AITokens = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_tokens_total",
+		Help: "Gemini tokens used, by model and kind: prompt or response.",
+	}, []string{"model", "kind"})
+
+	// CacheLookups counts cache lookups by cache (poster, film, watchlist) and result (hit, miss)
+	CacheLookups = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_lookups_total",
+		Help: "Cache lookups by cache (poster, film, watchlist) and result (hit, miss).",
+	}, []string{"cache", "result"})
+
+	// RateLimited counts requests refused by the rate limiter, by route pattern and policy
+	RateLimited = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limit_rejections_total",
+		Help: "Requests refused by the rate limiter, by route pattern and policy.",
+	}, []string{"route", "policy"})
+
+	// RateLimitClients is how many clients each in-memory rate limit policy is tracking
+	RateLimitClients = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rate_limit_clients",
+		Help: "Clients with an in-memory rate limit bucket, by policy. Idle clients are dropped once their bucket refills.",
+	}, []string{"policy"})
+)
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest records a served request under its mux pattern, e.g. "GET /api/v1/recommend",
+// which is labelled by its path. Requests that matched no route share the "none" route.
+func ObserveRequest(pattern, method string, code int, d time.Duration) {
+	route := routeLabel(pattern)
+	HTTPRequests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	HTTPDuration.WithLabelValues(route, method).Observe(d.Seconds())
+}
+
+// RejectRateLimited records a request to pattern refused by the rate limiter's policy
+func RejectRateLimited(pattern, policy string) {
+	RateLimited.WithLabelValues(routeLabel(pattern), policy).Inc()
+}
+
+// routeLabel drops the method from a mux pattern
+func routeLabel(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	if pattern == "" {
+		return "none"
+	}
+	return pattern
+}
+
+// CacheLookup records a hit or miss on cache
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheLookups.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,157 @@
+// Package ratelimit limits how often each client may call a group of endpoints, with a token
+// bucket per client for each policy. Buckets live in memory, per instance, or in Redis, shared
+// by every instance behind the load balancer.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-backend/internal/metrics"
+
+	"golang.org/x/time/rate"
+)
+
+// Policy allows Requests per Window to each client, with bursts of up to Requests
+type Policy struct {
+	Name     string
+	Requests int
+	Window   time.Duration
+}
+
+// Decision is the outcome of one request against a policy
+type Decision struct {
+	Allowed bool
+	Limit   int
+	// Remaining is how many requests could be made straight away
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed, 0 if it already would be
+	RetryAfter time.Duration
+}
+
+// Limiter enforces one policy
+type Limiter interface {
+	Policy() Policy
+	// Allow takes a request from key's bucket. An error means the bucket couldn't be reached
+	// and nothing was decided.
+	Allow(ctx context.Context, key string) (Decision, error)
+}
+
+// sweepInterval is how often idle clients are looked for
+const sweepInterval = time.Minute
+
+// Memory holds a bucket per client for one policy in this process. Clients are forgotten once
+// their bucket has refilled, which is the same as starting over, so memory only grows with
+// active clients.
+type Memory struct {
+	policy Policy
+	every  time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+type client struct {
+	bucket   *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewMemory returns a Memory limiter enforcing p
+func NewMemory(p Policy) *Memory {
+	return &Memory{
+		policy:    p,
+		every:     interval(p),
+		now:       time.Now,
+		clients:   make(map[string]*client),
+		lastSweep: time.Now(),
+	}
+}
+
+// Policy returns the policy the limiter enforces
+func (l *Memory) Policy() Policy {
+	return l.policy
+}
+
+// Allow takes a request from key's bucket, it never fails
+func (l *Memory) Allow(_ context.Context, key string) (Decision, error) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{bucket: rate.NewLimiter(rate.Every(l.every), l.policy.Requests)}
+		l.clients[key] = c
+		metrics.RateLimitClients.WithLabelValues(l.policy.Name).Set(float64(len(l.clients)))
+	}
+	c.lastSeen = now
+
+	allowed := c.bucket.AllowN(now, 1)
+	return decide(l.policy, l.every, allowed, c.bucket.TokensAt(now)), nil
+}
+
+// Len returns how many clients are being tracked
+func (l *Memory) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.clients)
+}
+
+// sweep forgets clients whose buckets have refilled since they were last seen
+func (l *Memory) sweep(now time.Time) {
+	for key, c := range l.clients {
+		if now.Sub(c.lastSeen) >= l.policy.Window {
+			delete(l.clients, key)
+		}
+	}
+	l.lastSweep = now
+	metrics.RateLimitClients.WithLabelValues(l.policy.Name).Set(float64(len(l.clients)))
+}
+
+// interval is how long p takes to refill one request
+func interval(p Policy) time.Duration {
+	return p.Window / time.Duration(p.Requests)
+}
+
+// decide describes a bucket left with tokens after a request, refilling one token every
+func decide(p Policy, every time.Duration, allowed bool, tokens float64) Decision {
+	d := Decision{
+		Allowed:   allowed,
+		Limit:     p.Requests,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     time.Duration((float64(p.Requests) - tokens) * float64(every)),
+	}
+	if tokens < 1 {
+		d.RetryAfter = time.Duration((1 - tokens) * float64(every))
+	}
+	return d
+}
+
+// SetHeaders describes d in the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers of the IETF draft, plus Retry-After when the request was refused
+func SetHeaders(h http.Header, p Policy, d Decision) {
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(d.Reset)))
+	h.Set("RateLimit-Policy", strconv.Itoa(p.Requests)+";w="+strconv.Itoa(seconds(p.Window)))
+	if !d.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(seconds(d.RetryAfter), 1)))
+	}
+}
+
+// seconds rounds d up to whole seconds, so clients waiting that long are never early
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
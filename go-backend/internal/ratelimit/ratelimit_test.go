@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newTestMemory returns a Memory limiter whose clock is *now
+func newTestMemory(p Policy, now *time.Time) *Memory {
+	l := NewMemory(p)
+	l.now = func() time.Time { return *now }
+	l.lastSweep = *now
+	return l
+}
+
+func TestMemoryBucket(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestMemory(Policy{Name: "ai", Requests: 3, Window: 3 * time.Minute}, &now)
+	ctx := context.Background()
+
+	for i := range 3 {
+		if d, _ := l.Allow(ctx, "1.2.3.4"); !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, d, 2-i)
+		}
+	}
+	d, _ := l.Allow(ctx, "1.2.3.4")
+	if d.Allowed || d.RetryAfter != time.Minute || d.Reset != 3*time.Minute {
+		t.Fatalf("4th request = %+v, want refused, retry in 1m and full in 3m", d)
+	}
+	if d, _ := l.Allow(ctx, "5.6.7.8"); !d.Allowed {
+		t.Fatalf("other client refused: %+v", d)
+	}
+
+	now = now.Add(time.Minute)
+	if d, _ := l.Allow(ctx, "1.2.3.4"); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("request after 1m = %+v, want allowed with 0 remaining", d)
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestMemory(Policy{Name: "default", Requests: 10, Window: 5 * time.Minute}, &now)
+	ctx := context.Background()
+
+	l.Allow(ctx, "idle")
+	now = now.Add(2 * time.Minute)
+	l.Allow(ctx, "active")
+	if n := l.Len(); n != 2 {
+		t.Fatalf("tracking %d clients, want 2", n)
+	}
+
+	// Sweeps run at most once a minute, and only forget buckets that have had a window to refill
+	now = now.Add(3 * time.Minute)
+	l.Allow(ctx, "active")
+	if n := l.Len(); n != 1 {
+		t.Fatalf("tracking %d clients after the sweep, want only the active one", n)
+	}
+
+	now = now.Add(30 * time.Second)
+	l.Allow(ctx, "new")
+	now = now.Add(6 * time.Minute)
+	l.Allow(ctx, "active")
+	if n := l.Len(); n != 1 {
+		t.Fatalf("tracking %d clients, want the idle ones forgotten", n)
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	p := Policy{Name: "ai", Requests: 10, Window: time.Hour}
+
+	h := http.Header{}
+	SetHeaders(h, p, Decision{Allowed: true, Limit: 10, Remaining: 7, Reset: 17*time.Minute + 500*time.Millisecond})
+	want := map[string]string{
+		"RateLimit-Limit":     "10",
+		"RateLimit-Remaining": "7",
+		"RateLimit-Reset":     "1021",
+		"RateLimit-Policy":    "10;w=3600",
+		"Retry-After":         "",
+	}
+	for k, v := range want {
+		if got := h.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	h = http.Header{}
+	SetHeaders(h, p, Decision{Limit: 10, Reset: time.Hour, RetryAfter: 200 * time.Millisecond})
+	if got := h.Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want it rounded up to 1", got)
+	}
+	if got := h.Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want 0", got)
+	}
+}
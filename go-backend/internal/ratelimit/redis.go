@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript is the token bucket in Redis: a hash of the tokens left and when they were
+// counted, refilled by the time since, in microseconds. Redis' own clock is used so replicas
+// with skewed clocks still agree. The key expires once the bucket would be full again.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local every = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) / every)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis keeps each client's bucket for one policy in Redis, so every instance sharing the
+// server shares the budget
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+	policy Policy
+	every  time.Duration
+}
+
+// NewRedis returns a Redis limiter enforcing p, with its keys under prefix
+func NewRedis(client redis.UniversalClient, prefix string, p Policy) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix + "ratelimit:" + p.Name + ":",
+		policy: p,
+		every:  interval(p),
+	}
+}
+
+// Policy returns the policy the limiter enforces
+func (l *Redis) Policy() Policy {
+	return l.policy
+}
+
+// Allow takes a request from key's bucket
+func (l *Redis) Allow(ctx context.Context, key string) (Decision, error) {
+	res, err := takeScript.Run(ctx, l.client, []string{l.prefix + key},
+		l.policy.Requests, l.every.Microseconds(), l.policy.Window.Milliseconds()).Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("rate limit %s: %w", l.policy.Name, err)
+	}
+	if len(res) != 2 {
+		return Decision{}, fmt.Errorf("rate limit %s: unexpected reply %v", l.policy.Name, res)
+	}
+	allowed, _ := res[0].(int64)
+	left, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return Decision{}, fmt.Errorf("rate limit %s: unexpected token count %q", l.policy.Name, left)
+	}
+	return decide(l.policy, l.every, allowed == 1, tokens), nil
+}
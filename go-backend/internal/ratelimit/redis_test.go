@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis returns a client for a fresh in-process Redis, whose clock starts at start
+func newTestRedis(t *testing.T, start time.Time) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(start)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+func TestRedisBucket(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	mr, client := newTestRedis(t, start)
+	l := NewRedis(client, "test:", Policy{Name: "ai", Requests: 3, Window: 3 * time.Minute})
+	ctx := context.Background()
+
+	for i := range 3 {
+		d, err := l.Allow(ctx, "1.2.3.4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, d, 2-i)
+		}
+	}
+
+	d, err := l.Allow(ctx, "1.2.3.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Allowed || d.RetryAfter != time.Minute || d.Reset != 3*time.Minute {
+		t.Fatalf("4th request = %+v, want refused, retry in 1m and full in 3m", d)
+	}
+
+	// Other clients have their own bucket
+	if d, _ := l.Allow(ctx, "5.6.7.8"); !d.Allowed {
+		t.Fatalf("other client refused: %+v", d)
+	}
+
+	// One token refills every minute, on Redis' clock
+	mr.SetTime(start.Add(90 * time.Second))
+	d, err = l.Allow(ctx, "1.2.3.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("request after 90s = %+v, want allowed with 0 remaining", d)
+	}
+}
+
+func TestRedisBucketKeys(t *testing.T) {
+	mr, client := newTestRedis(t, time.Now())
+	l := NewRedis(client, "test:", Policy{Name: "ai", Requests: 5, Window: time.Minute})
+
+	if _, err := l.Allow(context.Background(), "1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	key := "test:ratelimit:ai:1.2.3.4"
+	if !mr.Exists(key) {
+		t.Fatalf("bucket not stored under %s, keys: %v", key, mr.Keys())
+	}
+	// Gone once the bucket would be full again
+	if ttl := mr.TTL(key); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("bucket TTL = %s, want up to the window", ttl)
+	}
+	mr.FastForward(time.Minute)
+	if mr.Exists(key) {
+		t.Fatalf("bucket still stored after the window")
+	}
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	mr, client := newTestRedis(t, time.Now())
+	l := NewRedis(client, "test:", Policy{Name: "ai", Requests: 5, Window: time.Minute})
+	mr.Close()
+
+	if _, err := l.Allow(context.Background(), "1.2.3.4"); err == nil {
+		t.Fatal("Allow succeeded without Redis")
+	}
+}
@@ -0,0 +1,85 @@
+package scraper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ExportFormats are the formats NewFilmWriter accepts
+var ExportFormats = []string{"csv", "jsonl", "letterboxd"}
+
+// FilmWriter writes films one at a time, so long lists can be streamed
+type FilmWriter interface {
+	Write(f Film) error
+	// Flush pushes buffered output to the underlying writer
+	Flush() error
+	ContentType() string
+	// Extension is the file extension for downloads, without the dot
+	Extension() string
+}
+
+// NewFilmWriter returns a writer for format, writing any header straight away
+func NewFilmWriter(w io.Writer, format string) (FilmWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w, []string{"name", "year", "url", "image", "overview"}, func(f Film) []string {
+			return []string{f.Name, f.Year, f.Slug, f.Image, f.Overview}
+		})
+	case "letterboxd":
+		// The columns Letterboxd's importer matches films on, see letterboxd.com/about/importing-data/
+		return newCSVWriter(w, []string{"Title", "Year", "LetterboxdURI"}, func(f Film) []string {
+			return []string{f.Name, f.Year, f.Slug}
+		})
+	case "jsonl":
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+type csvWriter struct {
+	w   *csv.Writer
+	row func(Film) []string
+}
+
+func newCSVWriter(w io.Writer, header []string, row func(Film) []string) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), row: row}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (c *csvWriter) Write(f Film) error { return c.w.Write(c.row(f)) }
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) ContentType() string { return "text/csv; charset=utf-8" }
+func (c *csvWriter) Extension() string   { return "csv" }
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(f Film) error  { return j.enc.Encode(f) }
+func (j *jsonlWriter) Flush() error        { return nil }
+func (j *jsonlWriter) ContentType() string { return "application/x-ndjson" }
+func (j *jsonlWriter) Extension() string   { return "jsonl" }
+
+// WriteFilms writes every film in format and flushes
+func WriteFilms(w io.Writer, format string, films []Film) error {
+	fw, err := NewFilmWriter(w, format)
+	if err != nil {
+		return err
+	}
+	for _, f := range films {
+		if err := fw.Write(f); err != nil {
+			return err
+		}
+	}
+	return fw.Flush()
+}
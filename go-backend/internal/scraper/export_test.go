@@ -0,0 +1,97 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var exportFilms = []Film{
+	{Name: "Heat", Year: "1995", Slug: "https://letterboxd.com/film/heat-1995/", Image: "https://a.ltrbxd.com/heat.jpg", Overview: "A cop and a thief."},
+	{Name: "Crouching Tiger, Hidden Dragon", Year: "2000", Slug: "https://letterboxd.com/film/crouching-tiger-hidden-dragon/"},
+	{Name: `"Wild Strawberries"`, Year: "1957", Slug: "https://letterboxd.com/film/wild-strawberries/"},
+	{Name: "Untitled", Slug: "https://letterboxd.com/film/untitled/"},
+}
+
+func TestLetterboxdExport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFilms(&buf, "letterboxd", exportFilms); err != nil {
+		t.Fatal(err)
+	}
+
+	// Exactly what Letterboxd's importer expects, with commas and quotes in titles quoted
+	want := `Title,Year,LetterboxdURI
+Heat,1995,https://letterboxd.com/film/heat-1995/
+"Crouching Tiger, Hidden Dragon",2000,https://letterboxd.com/film/crouching-tiger-hidden-dragon/
+"""Wild Strawberries""",1957,https://letterboxd.com/film/wild-strawberries/
+Untitled,,https://letterboxd.com/film/untitled/
+`
+	if got := buf.String(); got != want {
+		t.Errorf("letterboxd export =\n%s\nwant\n%s", got, want)
+	}
+
+	// And reads back to the same films
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range exportFilms {
+		if got := rows[i+1]; !reflect.DeepEqual(got, []string{f.Name, f.Year, f.Slug}) {
+			t.Errorf("row %d reads back as %q", i+1, got)
+		}
+	}
+}
+
+func TestCSVExport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFilms(&buf, "csv", exportFilms[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `name,year,url,image,overview
+Heat,1995,https://letterboxd.com/film/heat-1995/,https://a.ltrbxd.com/heat.jpg,A cop and a thief.
+"Crouching Tiger, Hidden Dragon",2000,https://letterboxd.com/film/crouching-tiger-hidden-dragon/,,
+`
+	if got := buf.String(); got != want {
+		t.Errorf("csv export =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJSONLExport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFilms(&buf, "jsonl", exportFilms); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(exportFilms) {
+		t.Fatalf("got %d lines, want one per film:\n%s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		var f Film
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(f, exportFilms[i]) {
+			t.Errorf("line %d = %+v, want %+v", i+1, f, exportFilms[i])
+		}
+	}
+}
+
+func TestFilmWriterFormats(t *testing.T) {
+	for _, format := range ExportFormats {
+		fw, err := NewFilmWriter(&bytes.Buffer{}, format)
+		if err != nil {
+			t.Fatalf("NewFilmWriter(%s) = %v", format, err)
+		}
+		if fw.Extension() != format && !(format == "letterboxd" && fw.Extension() == "csv") {
+			t.Errorf("%s writer has extension %q", format, fw.Extension())
+		}
+	}
+	if _, err := NewFilmWriter(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("NewFilmWriter accepted an unknown format")
+	}
+}
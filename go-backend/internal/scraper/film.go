@@ -0,0 +1,123 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-backend/internal/tracing"
+
+	"github.com/gocolly/colly/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrFilmNotFound is returned when Letterboxd has no page for a film URL
+var ErrFilmNotFound = errors.New("film not found on Letterboxd")
+
+// FilmDetails is what a Letterboxd film page tells us about a film
+type FilmDetails struct {
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	Year     string `json:"year"`
+	Runtime  int    `json:"runtime"`
+	Image    string `json:"image"`
+	Overview string `json:"overview"`
+}
+
+var runtimePattern = regexp.MustCompile(`(\d+)\s*(?:&nbsp;|\x{00a0})?\s*mins?`)
+
+// GetFilmDetails visits a Letterboxd film page to confirm it exists and read its title, year, runtime and poster
+func GetFilmDetails(ctx context.Context, filmURL string) (_ *FilmDetails, err error) {
+	ctx, span := tracer.Start(ctx, "scraper.GetFilmDetails", trace.WithAttributes(
+		attribute.String("letterboxd.film", filmURL),
+	))
+	defer tracing.End(span, &err)
+	return getFilmDetails(ctx, filmURL)
+}
+
+func getFilmDetails(ctx context.Context, filmURL string) (*FilmDetails, error) {
+	slug := extractSlugFromURL(filmURL)
+	if slug == "" {
+		return nil, fmt.Errorf("%w: %s is not a Letterboxd film URL", ErrFilmNotFound, filmURL)
+	}
+
+	details := &FilmDetails{URL: filmURL}
+	var mu sync.Mutex
+	var visitErr error
+
+	c := colly.NewCollector(
+		colly.StdlibContext(ctx),
+		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+		// Redirects are only followed within Letterboxd
+		colly.AllowedDomains("letterboxd.com"),
+	)
+	instrument(c, "film")
+
+	// og:title is "Title (Year)"
+	c.OnHTML("meta[property='og:title']", func(e *colly.HTMLElement) {
+		title := e.Attr("content")
+		year := ""
+		if i := strings.LastIndex(title, " ("); i > 0 && strings.HasSuffix(title, ")") {
+			year = title[i+2 : len(title)-1]
+			title = title[:i]
+		}
+		mu.Lock()
+		details.Title = title
+		details.Year = year
+		mu.Unlock()
+	})
+
+	c.OnHTML("meta[property='og:image']", func(e *colly.HTMLElement) {
+		mu.Lock()
+		details.Image = e.Attr("content")
+		mu.Unlock()
+	})
+
+	c.OnHTML("meta[name='description']", func(e *colly.HTMLElement) {
+		mu.Lock()
+		details.Overview = e.Attr("content")
+		mu.Unlock()
+	})
+
+	// The footer reads "123 mins   More at IMDb TMDb"
+	c.OnHTML("p.text-link.text-footer", func(e *colly.HTMLElement) {
+		if m := runtimePattern.FindStringSubmatch(e.Text); m != nil {
+			if runtime, err := strconv.Atoi(m[1]); err == nil {
+				mu.Lock()
+				details.Runtime = runtime
+				mu.Unlock()
+			}
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		if r != nil && r.StatusCode == http.StatusNotFound {
+			visitErr = fmt.Errorf("%w: %s", ErrFilmNotFound, filmURL)
+			return
+		}
+		visitErr = fmt.Errorf("failed to fetch %s: %w", filmURL, err)
+	})
+
+	slog.DebugContext(ctx, "Fetching film details", "url", filmURL)
+	if err := c.Visit(FilmURL(slug)); err != nil && visitErr == nil {
+		visitErr = fmt.Errorf("failed to fetch %s: %w", filmURL, err)
+	}
+	c.Wait()
+
+	if visitErr != nil {
+		return nil, visitErr
+	}
+	if details.Title == "" {
+		return nil, fmt.Errorf("%w: %s has no title", ErrFilmNotFound, filmURL)
+	}
+
+	slog.DebugContext(ctx, "Film details", "url", filmURL, "title", details.Title, "year", details.Year, "runtime", details.Runtime)
+	return details, nil
+}
@@ -0,0 +1,108 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Letterboxd's full size poster, the size the watchlist and AJAX scrapers ask for
+const (
+	largePosterWidth  = 2000
+	largePosterHeight = 3000
+)
+
+// The CDNs whose URLs encode the image size, nothing else is rewritten
+const (
+	letterboxdImageHost = "a.ltrbxd.com"
+	tmdbImageHost       = "image.tmdb.org"
+)
+
+// letterboxdSizePattern matches the size suffix of Letterboxd's resized images, e.g.
+// ".../51528-pierrot-le-fou-0-125-0-187-crop.jpg"
+var letterboxdSizePattern = regexp.MustCompile(`-0-(\d+)-0-(\d+)(-crop)?(\.[a-z]+)$`)
+
+// tmdbSizePattern matches the size segment of TMDB image URLs, e.g. "/t/p/w500/abc.jpg"
+var tmdbSizePattern = regexp.MustCompile(`^(.*/t/p/)(w\d+|h\d+|original)(/[^/]+)$`)
+
+// tmdbPosterWidths are the poster sizes TMDB serves
+var tmdbPosterWidths = []int{92, 154, 185, 342, 500, 780}
+
+// ImageURL is a poster URL from a CDN that serves resized variants
+type ImageURL struct {
+	u      *url.URL
+	host   string // "letterboxd" or "tmdb"
+	Width  int
+	Height int
+}
+
+// ParseImageURL recognises Letterboxd's "-0-W-0-H-crop.jpg" and TMDB's "/t/p/wNNN/" URLs on
+// their CDN hosts. Width or Height is 0 when the URL doesn't say, e.g. TMDB's "original".
+func ParseImageURL(raw string) (*ImageURL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if m := letterboxdSizePattern.FindStringSubmatch(u.Path); m != nil && host == letterboxdImageHost {
+		width, _ := strconv.Atoi(m[1])
+		height, _ := strconv.Atoi(m[2])
+		return &ImageURL{u: u, host: "letterboxd", Width: width, Height: height}, nil
+	}
+
+	if m := tmdbSizePattern.FindStringSubmatch(u.Path); m != nil && host == tmdbImageHost {
+		img := &ImageURL{u: u, host: "tmdb"}
+		switch m[2][0] {
+		case 'w':
+			img.Width, _ = strconv.Atoi(m[2][1:])
+		case 'h':
+			img.Height, _ = strconv.Atoi(m[2][1:])
+		}
+		return img, nil
+	}
+
+	return nil, fmt.Errorf("not a resizable image URL: %s", raw)
+}
+
+// Resize returns the URL of the same image at width x height. Letterboxd crops to any size,
+// TMDB only serves fixed widths so the next one up is used, or the original when none is big enough.
+func (img *ImageURL) Resize(width, height int) string {
+	u := *img.u
+
+	switch img.host {
+	case "letterboxd":
+		m := letterboxdSizePattern.FindStringSubmatchIndex(u.Path)
+		suffix := fmt.Sprintf("-0-%d-0-%d", width, height)
+		if m[6] >= 0 {
+			suffix += u.Path[m[6]:m[7]]
+		}
+		u.Path = u.Path[:m[0]] + suffix + u.Path[m[8]:m[9]]
+		u.RawPath = ""
+
+	case "tmdb":
+		size := "original"
+		for _, w := range tmdbPosterWidths {
+			if w >= width {
+				size = "w" + strconv.Itoa(w)
+				break
+			}
+		}
+		u.Path = tmdbSizePattern.ReplaceAllString(u.Path, "${1}"+size+"${3}")
+		u.RawPath = ""
+	}
+
+	return u.String()
+}
+
+// ResizeImageURL rewrites a Letterboxd or TMDB image URL to width x height, returning any other
+// URL unchanged
+func ResizeImageURL(raw string, width, height int) string {
+	img, err := ParseImageURL(raw)
+	if err != nil {
+		return raw
+	}
+	return img.Resize(width, height)
+}
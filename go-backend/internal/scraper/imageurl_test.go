@@ -0,0 +1,59 @@
+package scraper
+
+import "testing"
+
+func TestResizeImageURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		// Only the size suffix changes, the film ID and the digits of its path are left alone
+		{"https://a.ltrbxd.com/resized/film-poster/5/1/5/2/8/51528-pierrot-le-fou-0-125-0-187-crop.jpg?v=1",
+			"https://a.ltrbxd.com/resized/film-poster/5/1/5/2/8/51528-pierrot-le-fou-0-2000-0-3000-crop.jpg?v=1"},
+		{"https://a.ltrbxd.com/resized/film-poster/1/2/5/0/125-1250-0-125-0-187.jpg",
+			"https://a.ltrbxd.com/resized/film-poster/1/2/5/0/125-1250-0-2000-0-3000.jpg"},
+		{"https://a.ltrbxd.com/resized/sm/upload/0-125-0-187/0-125-0-187-0-230-0-345-crop.jpg",
+			"https://a.ltrbxd.com/resized/sm/upload/0-125-0-187/0-125-0-187-0-2000-0-3000-crop.jpg"},
+		{"https://image.tmdb.org/t/p/w185/w500abc185.jpg", "https://image.tmdb.org/t/p/original/w500abc185.jpg"},
+		{"https://image.tmdb.org/t/p/original/abc.jpg", "https://image.tmdb.org/t/p/original/abc.jpg"},
+
+		// Other hosts are never rewritten, whatever their paths look like
+		{"https://example.com/poster-0-125-0-187-crop.jpg", "https://example.com/poster-0-125-0-187-crop.jpg"},
+		{"https://a.ltrbxd.com.example.com/x-0-125-0-187.jpg", "https://a.ltrbxd.com.example.com/x-0-125-0-187.jpg"},
+		{"https://example.com/t/p/w500/abc.jpg", "https://example.com/t/p/w500/abc.jpg"},
+		{"not a url", "not a url"},
+	}
+	for _, tt := range tests {
+		if got := ResizeImageURL(tt.url, largePosterWidth, largePosterHeight); got != tt.want {
+			t.Errorf("ResizeImageURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseImageURL(t *testing.T) {
+	img, err := ParseImageURL("https://a.ltrbxd.com/resized/film-poster/9/4/9/949-heat-0-230-0-345-crop.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 230 || img.Height != 345 {
+		t.Errorf("Letterboxd size = %dx%d, want 230x345", img.Width, img.Height)
+	}
+	if got := img.Resize(500, 750); got != "https://a.ltrbxd.com/resized/film-poster/9/4/9/949-heat-0-500-0-750-crop.jpg" {
+		t.Errorf("Resize(500, 750) = %q", got)
+	}
+
+	img, err = ParseImageURL("https://image.tmdb.org/t/p/w342/abc.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 342 || img.Height != 0 {
+		t.Errorf("TMDB size = %dx%d, want 342x0", img.Width, img.Height)
+	}
+	if got := img.Resize(400, 600); got != "https://image.tmdb.org/t/p/w500/abc.jpg" {
+		t.Errorf("Resize(400, 600) = %q", got)
+	}
+
+	if _, err := ParseImageURL("https://example.com/t/p/w342/abc.jpg"); err == nil {
+		t.Error("ParseImageURL accepted a TMDB path on another host")
+	}
+}
@@ -0,0 +1,30 @@
+package scraper
+
+import (
+	"strconv"
+
+	"go-backend/internal/metrics"
+	"go-backend/internal/tracing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// tracer starts the scraper's spans; each page a collector fetches is a client span below them
+var tracer = tracing.Tracer("go-backend/internal/scraper")
+
+// instrument traces every request c sends, and counts the pages it fetches under name and every
+// Letterboxd status code it sees
+func instrument(c *colly.Collector, name string) {
+	c.WithTransport(tracing.Transport(nil))
+	c.OnResponse(func(r *colly.Response) {
+		metrics.ScraperPages.WithLabelValues(name).Inc()
+		metrics.LetterboxdResponses.WithLabelValues(strconv.Itoa(r.StatusCode)).Inc()
+	})
+	c.OnError(func(r *colly.Response, err error) {
+		code := "error"
+		if r.StatusCode != 0 {
+			code = strconv.Itoa(r.StatusCode)
+		}
+		metrics.LetterboxdResponses.WithLabelValues(code).Inc()
+	})
+}
@@ -2,32 +2,57 @@ package scraper
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"go-backend/internal/metrics"
+	"go-backend/internal/tracing"
+
 	"github.com/gocolly/colly/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Film struct {
-	Name     string `json:"name"`
-	Slug     string `json:"slug"`
-	Image    string `json:"image"`
-	Year     string `json:"year"`
-	FilmPath string `json:"filmPath"`
-	Overview string `json:"overview"`
+	Name     string   `json:"name"`
+	Slug     string   `json:"slug"`
+	Image    string   `json:"image"`
+	Year     string   `json:"year"`
+	FilmPath string   `json:"filmPath"`
+	Overview string   `json:"overview"`
+	Blurhash string   `json:"blurhash,omitempty"`
+	Palette  []string `json:"palette,omitempty"`
+	ShareID  string   `json:"share_id,omitempty"`
 }
 
 // ScrapeWatchlist scrapes a Letterboxd watchlist using Colly with high parallelism
 func ScrapeWatchlist(username, genres string) ([]Film, error) {
+	return ScrapeWatchlistContext(context.Background(), username, genres)
+}
+
+// ScrapeWatchlistContext is ScrapeWatchlist, stopping early with parent's error when parent is done
+func ScrapeWatchlistContext(parent context.Context, username, genres string) (films []Film, err error) {
+	parent, span := tracer.Start(parent, "scraper.ScrapeWatchlist", trace.WithAttributes(
+		attribute.String("letterboxd.username", username),
+		attribute.String("letterboxd.genres", genres),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("letterboxd.films", len(films)))
+		tracing.End(span, &err)
+	}()
+	return scrapeWatchlist(parent, username, genres)
+}
+
+func scrapeWatchlist(parent context.Context, username, genres string) ([]Film, error) {
 	// Add timeout context - 8 seconds max to leave buffer for Netlify's 10-second limit
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	ctx, cancel := context.WithTimeout(parent, 8*time.Second)
 	defer cancel()
 
 	var films []Film
@@ -38,8 +63,8 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 	// Build start URL
 	startURL := "https://letterboxd.com/" + username + "/watchlist/"
 	if genres != "" {
-		genreSlugs := convertGenreIDs(genres)
-		log.Printf("DEBUG: Converting genres '%s' to slugs: %v", genres, genreSlugs)
+		genreSlugs := convertGenreIDs(ctx, genres)
+		slog.DebugContext(ctx, "Converted genres", "genres", genres, "slugs", genreSlugs)
 		if len(genreSlugs) > 0 {
 			startURL = startURL + "genre/" + strings.Join(genreSlugs, "+") + "/"
 		} else {
@@ -47,18 +72,22 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 		}
 	}
 
-	log.Printf("DEBUG: Scraping watchlist from URL: %s", startURL)
+	slog.DebugContext(ctx, "Scraping watchlist", "url", startURL)
 
 	// Secondary collector for AJAX poster endpoints (following original repo pattern)
 	ajc := colly.NewCollector(
+		colly.StdlibContext(ctx),
 		colly.Async(true),
 	)
+	instrument(ajc, "ajax")
 	ajc.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 50}) // Reduced from 100
 
 	// Film detail collector for overview and better poster data - OPTIMIZED
 	filmCollector := colly.NewCollector(
+		colly.StdlibContext(ctx),
 		colly.Async(true),
 	)
+	instrument(filmCollector, "film")
 	filmCollector.Limit(&colly.LimitRule{
 		DomainGlob:  "*letterboxd.com*",
 		Parallelism: 10,                    // Reduced from 20 to 10
@@ -89,7 +118,7 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 
 		fullSlug := "https://letterboxd.com" + slug
 
-		log.Printf("DEBUG: AJAX found poster for %s: %s", name, img)
+		slog.DebugContext(ctx, "AJAX found poster", "film", name, "image", img)
 
 		// Check if already processed this film
 		mu.Lock()
@@ -113,7 +142,7 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 		films = append(films, Film{
 			Name:     name,
 			Slug:     fullSlug,
-			Image:    makeBigger(img), // Real poster URL from AJAX
+			Image:    ResizeImageURL(img, largePosterWidth, largePosterHeight), // Real poster URL from AJAX
 			Year:     year,
 			FilmPath: slug,
 			Overview: "",
@@ -142,7 +171,7 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 
 		overview := e.Text
 
-		log.Printf("DEBUG: Found overview for %s: %s", e.Request.URL.String(), overview[:min(len(overview), 50)])
+		slog.DebugContext(ctx, "Found overview", "url", e.Request.URL.String())
 
 		// Update the film with overview
 		mu.Lock()
@@ -166,7 +195,7 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 
 		overview := e.Text
 		if overview != "" {
-			log.Printf("DEBUG: Found overview (alt selector) for %s: %s", e.Request.URL.String(), overview[:min(len(overview), 50)])
+			slog.DebugContext(ctx, "Found overview", "url", e.Request.URL.String(), "selector", "alt")
 
 			// Update the film with overview
 			mu.Lock()
@@ -191,7 +220,7 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 
 		overview := e.Text
 		if overview != "" {
-			log.Printf("DEBUG: Found overview (data-testid) for %s: %s", e.Request.URL.String(), overview[:min(len(overview), 50)])
+			slog.DebugContext(ctx, "Found overview", "url", e.Request.URL.String(), "selector", "data-testid")
 
 			// Update the film with overview
 			mu.Lock()
@@ -216,7 +245,7 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 
 		overview := e.Attr("content")
 		if overview != "" {
-			log.Printf("DEBUG: Found meta description for %s: %s", e.Request.URL.String(), overview[:min(len(overview), 50)])
+			slog.DebugContext(ctx, "Found overview", "url", e.Request.URL.String(), "selector", "meta description")
 
 			// Update the film with overview if we don't have one yet
 			mu.Lock()
@@ -241,7 +270,7 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 
 		img := e.Attr("content")
 
-		log.Printf("DEBUG: Found og:image for %s: %s", e.Request.URL.String(), img)
+		slog.DebugContext(ctx, "Found og:image", "url", e.Request.URL.String(), "image", img)
 
 		// Update the film with og:image (reliable image URL)
 		mu.Lock()
@@ -256,9 +285,11 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 
 	// Main collector for the watchlist page (following original repo pattern)
 	c := colly.NewCollector(
+		colly.StdlibContext(ctx),
 		colly.Async(true),
 		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
 	)
+	instrument(c, "watchlist")
 	c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 100})
 
 	// HTML selector for containers holding film posters (following original repo pattern exactly)
@@ -267,7 +298,7 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 		e.ForEach("div.film-poster", func(i int, ein *colly.HTMLElement) {
 			slug := ein.Attr("data-target-link")
 			if slug != "" {
-				log.Printf("DEBUG: Found film with slug: %s", slug)
+				slog.DebugContext(ctx, "Found film", "slug", slug)
 				ajc.Visit(urlscrape + slug + urlEnd)
 			}
 		})
@@ -277,39 +308,35 @@ func ScrapeWatchlist(username, genres string) ([]Film, error) {
 	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
 		link := e.Attr("href")
 		if strings.Contains(link, "/page") {
-			log.Printf("DEBUG: Following pagination to: %s", e.Request.AbsoluteURL(link))
+			slog.DebugContext(ctx, "Following pagination", "url", e.Request.AbsoluteURL(link))
 			e.Request.Visit(e.Request.AbsoluteURL(link))
 		}
 	})
 
 	// Start scraping
-	log.Printf("DEBUG: Starting Colly watchlist scrape for %s", startURL)
 	c.Visit(startURL)
 	c.Wait()
 	ajc.Wait()
 	filmCollector.Wait()
 
-	// Set default poster for films without images
+	// Running out of our own time still returns what was found, the caller going away does not
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+
+	// Letterboxd's own "no poster" images count as no poster, callers fill those in
 	for i := range films {
-		if films[i].Image == "" || isEmptyPoster(films[i].Image) {
-			films[i].Image = "https://watchlistpicker.com/noimagefound.jpg"
+		if isEmptyPoster(films[i].Image) {
+			films[i].Image = ""
 		}
 	}
 
-	log.Printf("DEBUG: Colly scrape complete, found %d films", len(films))
+	slog.InfoContext(ctx, "Watchlist scraped", "films", len(films))
 	return films, nil
 }
 
-// Helper function for min
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 // convertGenreIDs converts a comma-separated string of genre IDs to Letterboxd slugs
-func convertGenreIDs(genres string) []string {
+func convertGenreIDs(ctx context.Context, genres string) []string {
 	genreIdToSlug := map[string]string{
 		"28": "action", "12": "adventure", "16": "animation", "35": "comedy",
 		"80": "crime", "99": "documentary", "18": "drama", "10751": "family",
@@ -323,7 +350,7 @@ func convertGenreIDs(genres string) []string {
 		if slug, ok := genreIdToSlug[id]; ok {
 			slugs = append(slugs, slug)
 		} else {
-			log.Printf("DEBUG: Unknown genre ID: %s", id)
+			slog.DebugContext(ctx, "Unknown genre ID", "id", id)
 		}
 	}
 	return slugs
@@ -372,230 +399,95 @@ func isEmptyPoster(url string) bool {
 	return false
 }
 
-// GetPosterFromTMDB gets movie poster from TMDB API (fast and reliable)
-func GetPosterFromTMDB(movieName, year string) (string, error) {
-	// TMDB API key - you'll need to get a free one from https://www.themoviedb.org/settings/api
-	tmdbAPIKey := os.Getenv("TMDB_API_KEY")
-	if tmdbAPIKey == "" {
-		return "", fmt.Errorf("TMDB_API_KEY not set")
-	}
-
-	// Search for the movie
-	searchURL := fmt.Sprintf("https://api.themoviedb.org/3/search/movie?api_key=%s&query=%s&year=%s",
-		tmdbAPIKey, url.QueryEscape(movieName), year)
+// filmSlugPattern matches Letterboxd film slugs such as "the-shining" or "heat-1995"
+var filmSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
 
-	resp, err := http.Get(searchURL)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Results []struct {
-			PosterPath string `json:"poster_path"`
-		} `json:"results"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
-
-	if len(result.Results) > 0 && result.Results[0].PosterPath != "" {
-		// Return high quality poster URL
-		posterURL := fmt.Sprintf("https://image.tmdb.org/t/p/w500%s", result.Results[0].PosterPath)
-		log.Printf("DEBUG: TMDB found poster for %s: %s", movieName, posterURL)
-		return posterURL, nil
-	}
-
-	return "", fmt.Errorf("no poster found for %s", movieName)
+// IsFilmSlug reports whether s looks like a Letterboxd film slug
+func IsFilmSlug(s string) bool {
+	return filmSlugPattern.MatchString(s)
 }
 
-// GetPosterFromTMDBByID gets movie poster from TMDB API using the movie ID (most reliable)
-func GetPosterFromTMDBByID(tmdbID string) (string, error) {
-	// TMDB API key - you'll need to get a free one from https://www.themoviedb.org/settings/api
-	tmdbAPIKey := os.Getenv("TMDB_API_KEY")
-	if tmdbAPIKey == "" {
-		return "", fmt.Errorf("TMDB_API_KEY not set")
-	}
-
-	// Get movie details directly by ID
-	movieURL := fmt.Sprintf("https://api.themoviedb.org/3/movie/%s?api_key=%s",
-		tmdbID, tmdbAPIKey)
+// FilmURL returns the Letterboxd page of the film with slug
+func FilmURL(slug string) string {
+	return "https://letterboxd.com/film/" + slug + "/"
+}
 
-	resp, err := http.Get(movieURL)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+// FilmSlug returns the slug of a Letterboxd film URL, or "" for anything else
+func FilmSlug(filmURL string) string {
+	return extractSlugFromURL(filmURL)
+}
 
-	var result struct {
-		PosterPath string `json:"poster_path"`
-		Title      string `json:"title"`
+// extractSlugFromURL extracts the film slug from a Letterboxd URL such as
+// "https://letterboxd.com/film/pierrot-le-fou/". Film URLs come from the model, so only
+// letterboxd.com itself counts; anything else would point the scrapers at any host it named.
+func extractSlugFromURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host != "letterboxd.com" {
+		return ""
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	rest, ok := strings.CutPrefix(u.Path, "/film/")
+	if !ok {
+		return ""
 	}
-
-	if result.PosterPath != "" {
-		// Return high quality poster URL
-		posterURL := fmt.Sprintf("https://image.tmdb.org/t/p/w500%s", result.PosterPath)
-		log.Printf("DEBUG: TMDB found poster for %s (ID: %s): %s", result.Title, tmdbID, posterURL)
-		return posterURL, nil
+	slug := strings.TrimSuffix(rest, "/")
+	if !IsFilmSlug(slug) {
+		return ""
 	}
-
-	return "", fmt.Errorf("no poster found for movie ID %s", tmdbID)
+	return slug
 }
 
-// GetPoster scrapes a single poster using the working AJAX approach
-func GetPoster(url string) (*PosterData, error) {
-	var posterURL, overview string
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	// ajax kinda making me wanna play football with my head
-	// Extract film slug from URL (e.g., "https://letterboxd.com/film/pierrot-le-fou/" -> "pierrot-le-fou")
-	slug := extractSlugFromURL(url)
-	if slug == "" {
-		return &PosterData{
-			PosterURL: "https://watchlistpicker.com/noimagefound.jpg",
-			Overview:  "",
-		}, nil
-	}
-
-	// AJAX poster endpoint constants (same as watchlist)
-	const urlscrape = "https://letterboxd.com/ajax/poster"
-	const urlEnd = "/std/125x187/"
-
-	// Create a collector for AJAX poster endpoint with timeout
-	c := colly.NewCollector(
-		colly.Async(true),
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-	)
-	c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 1})
-
-	// Extract poster data from AJAX endpoint (same as watchlist)
-	c.OnHTML("div.film-poster", func(e *colly.HTMLElement) {
-		img := e.ChildAttr("img", "src")
-
-		if img != "" {
-			mu.Lock()
-			posterURL = makeBigger(img) // Apply makeBigger for larger images
-			mu.Unlock()
-			log.Printf("DEBUG: AJAX found poster for %s: %s", slug, img)
-		}
-
-		wg.Done()
-	})
-
-	// Visit the AJAX poster endpoint
-	ajaxURL := urlscrape + "/" + slug + urlEnd
-	log.Printf("DEBUG: Visiting AJAX poster endpoint for %s: %s", slug, ajaxURL)
-	c.Visit(ajaxURL)
-
-	// Wait with timeout
-	done := make(chan bool, 1)
-	go func() {
-		c.Wait()
-		done <- true
-	}()
+// probeClient sends Probe's requests, bounded by the caller's context
+var probeClient = tracing.HTTPClient(0)
 
-	select {
-	case <-done:
-		// AJAX completed
-	case <-time.After(5 * time.Second):
-		// AJAX timed out, try fallback
-		log.Printf("DEBUG: AJAX timed out for %s, trying fallback", slug)
-	}
-
-	// If AJAX didn't work, try fallback to og:image from the main page
-	if posterURL == "" || isEmptyPoster(posterURL) {
-		log.Printf("DEBUG: AJAX failed for %s, trying og:image fallback", slug)
-
-		// Create a new collector for the main film page
-		fallbackCollector := colly.NewCollector(
-			colly.Async(true),
-			colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-		)
-		fallbackCollector.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 1})
-
-		// Try to get og:image
-		fallbackCollector.OnHTML("meta[property='og:image']", func(e *colly.HTMLElement) {
-			img := e.Attr("content")
-			if img != "" {
-				mu.Lock()
-				posterURL = img
-				mu.Unlock()
-				log.Printf("DEBUG: Found og:image fallback for %s: %s", slug, img)
-			}
-		})
-
-		fallbackCollector.Visit(url)
-		fallbackCollector.Wait()
+// Probe checks Letterboxd answers with a HEAD of its home page. Any response short of a server
+// error counts, as Letterboxd sometimes turns away requests it takes for bots.
+func Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://letterboxd.com/", nil)
+	if err != nil {
+		return err
 	}
-
-	// Set default if no poster found
-	if posterURL == "" || isEmptyPoster(posterURL) {
-		posterURL = "https://watchlistpicker.com/noimagefound.jpg"
-		log.Printf("DEBUG: Using default poster URL - no poster found for %s", slug)
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		metrics.LetterboxdResponses.WithLabelValues("error").Inc()
+		return fmt.Errorf("Letterboxd unreachable: %w", err)
 	}
-
-	log.Printf("DEBUG: Final poster URL for %s: %s", slug, posterURL)
-
-	return &PosterData{
-		PosterURL: posterURL,
-		Overview:  overview,
-	}, nil
-}
-
-// extractSlugFromURL extracts the film slug from a Letterboxd URL
-func extractSlugFromURL(url string) string {
-	// Handle URLs like "https://letterboxd.com/film/pierrot-le-fou/"
-	if strings.Contains(url, "/film/") {
-		parts := strings.Split(url, "/film/")
-		if len(parts) > 1 {
-			slug := strings.TrimSuffix(parts[1], "/")
-			return slug
-		}
+	resp.Body.Close()
+	metrics.LetterboxdResponses.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("Letterboxd returned %d", resp.StatusCode)
 	}
-	return ""
+	return nil
 }
 
-type PosterData struct {
-	PosterURL string `json:"poster"`
-	Overview  string `json:"overview"`
+// GetWatchlist scrapes every film on a watchlist, visiting each film page for its poster and overview
+func GetWatchlist(username string) ([]Film, error) {
+	return GetWatchlistContext(context.Background(), username)
 }
 
-func makeBigger(img string) string {
-	if img == "" {
-		return ""
-	}
-
-	if strings.Contains(img, "2000") || strings.Contains(img, "3000") {
-		return img
-	}
-
-	img = strings.ReplaceAll(img, "230", "2000")
-	img = strings.ReplaceAll(img, "345", "3000")
-	img = strings.ReplaceAll(img, "125", "2000")
-	img = strings.ReplaceAll(img, "187", "3000")
-	img = strings.ReplaceAll(img, "1000", "2000")
-	img = strings.ReplaceAll(img, "1500", "3000")
-
-	return img
+// GetWatchlistContext is GetWatchlist, stopping early when ctx is done
+func GetWatchlistContext(ctx context.Context, username string) (films []Film, err error) {
+	ctx, span := tracer.Start(ctx, "scraper.GetWatchlist", trace.WithAttributes(
+		attribute.String("letterboxd.username", username),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("letterboxd.films", len(films)))
+		tracing.End(span, &err)
+	}()
+	return getWatchlist(ctx, username)
 }
 
-func GetWatchlist(username string) ([]Film, error) {
+func getWatchlist(ctx context.Context, username string) ([]Film, error) {
 	var films []Film
 	var mu sync.Mutex
 	processedFilms := make(map[string]bool)
 
 	// primary collector for watchlist pages
 	c := colly.NewCollector(
+		colly.StdlibContext(ctx),
 		colly.Async(true),
 		colly.MaxDepth(2),
 	)
+	instrument(c, "watchlist")
 	c.Limit(&colly.LimitRule{
 		DomainGlob:  "*letterboxd.com*",
 		Parallelism: 100,
@@ -604,8 +496,10 @@ func GetWatchlist(username string) ([]Film, error) {
 
 	// secondary collector for film detail pages for poster data ofc
 	filmCollector := colly.NewCollector(
+		colly.StdlibContext(ctx),
 		colly.Async(true),
 	)
+	instrument(filmCollector, "film")
 	filmCollector.Limit(&colly.LimitRule{
 		DomainGlob:  "*letterboxd.com*",
 		Parallelism: 50,
@@ -637,14 +531,14 @@ func GetWatchlist(username string) ([]Film, error) {
 		processedFilms[fullSlug] = true
 		mu.Unlock()
 
-		log.Printf("DEBUG: Found film: %s at %s", name, fullSlug)
+		slog.DebugContext(ctx, "Found film", "film", name, "url", fullSlug)
 
 		// Add film to list with initial data
 		mu.Lock()
 		films = append(films, Film{
 			Name:     name,
 			Slug:     fullSlug,
-			Image:    makeBigger(img), // Initial image from watchlist
+			Image:    ResizeImageURL(img, largePosterWidth, largePosterHeight), // Initial image from watchlist
 			Year:     year,
 			FilmPath: filmPath,
 			Overview: "",
@@ -659,7 +553,7 @@ func GetWatchlist(username string) ([]Film, error) {
 	filmCollector.OnHTML(".film-overview p", func(e *colly.HTMLElement) {
 		overview := e.Text
 
-		log.Printf("DEBUG: Found overview for %s: %s", e.Request.URL.String(), overview[:min(len(overview), 50)])
+		slog.DebugContext(ctx, "Found overview", "url", e.Request.URL.String())
 
 		// Update the film with overview
 		mu.Lock()
@@ -676,7 +570,7 @@ func GetWatchlist(username string) ([]Film, error) {
 	filmCollector.OnHTML("meta[property='og:image']", func(e *colly.HTMLElement) {
 		img := e.Attr("content")
 
-		log.Printf("DEBUG: Found og:image for %s: %s", e.Request.URL.String(), img)
+		slog.DebugContext(ctx, "Found og:image", "url", e.Request.URL.String(), "image", img)
 
 		// Update the film with og:image (reliable image URL)
 		mu.Lock()
@@ -693,14 +587,14 @@ func GetWatchlist(username string) ([]Film, error) {
 	c.OnHTML("a.next", func(e *colly.HTMLElement) {
 		nextPage := e.Attr("href")
 		if nextPage != "" {
-			log.Printf("DEBUG: Following pagination to: %s", nextPage)
+			slog.DebugContext(ctx, "Following pagination", "url", nextPage)
 			c.Visit("https://letterboxd.com" + nextPage)
 		}
 	})
 
 	// Start scraping
 	watchlistURL := fmt.Sprintf("https://letterboxd.com/%s/watchlist/", username)
-	log.Printf("DEBUG: Starting scrape of %s", watchlistURL)
+	slog.DebugContext(ctx, "Scraping watchlist", "url", watchlistURL)
 
 	err := c.Visit(watchlistURL)
 	if err != nil {
@@ -711,79 +605,10 @@ func GetWatchlist(username string) ([]Film, error) {
 	c.Wait()
 	filmCollector.Wait()
 
-	log.Printf("DEBUG: Colly scrape complete, found %d films", len(films))
-	return films, nil
-}
-
-// just doesnt work why does tmdb even exist or maybe i just suck at coding
-// GetPosterFromTMDBImages gets movie posters from TMDB API using the images endpoint
-func GetPosterFromTMDBImages(tmdbID string) (string, error) {
-	// TMDB API key - you'll need to get a free one from https://www.themoviedb.org/settings/api
-	tmdbAPIKey := os.Getenv("TMDB_API_KEY")
-	if tmdbAPIKey == "" {
-		return "", fmt.Errorf("TMDB_API_KEY not set")
-	}
-
-	imagesURL := fmt.Sprintf("https://api.themoviedb.org/3/movie/%s/images", tmdbID)
-
-	resp, err := http.Get(imagesURL)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Posters []struct {
-			FilePath    string  `json:"file_path"`
-			VoteAverage float64 `json:"vote_average"`
-			VoteCount   int     `json:"vote_count"`
-			Width       int     `json:"width"`
-			Height      int     `json:"height"`
-		} `json:"posters"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
-
-	if len(result.Posters) == 0 {
-		return "", fmt.Errorf("no posters found for movie ID %s", tmdbID)
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Find the best poster (highest vote average, then highest vote count)
-	var bestPoster struct {
-		FilePath    string
-		VoteAverage float64
-		VoteCount   int
-	}
-
-	for _, poster := range result.Posters {
-		// Skip posters that are too small
-		if poster.Width < 500 || poster.Height < 750 {
-			continue
-		}
-
-		// Choose the poster with highest vote average, then highest vote count
-		if poster.VoteAverage > bestPoster.VoteAverage ||
-			(poster.VoteAverage == bestPoster.VoteAverage && poster.VoteCount > bestPoster.VoteCount) {
-			bestPoster.FilePath = poster.FilePath
-			bestPoster.VoteAverage = poster.VoteAverage
-			bestPoster.VoteCount = poster.VoteCount
-		}
-	}
-
-	// If no suitable poster found, use the first one
-	if bestPoster.FilePath == "" && len(result.Posters) > 0 {
-		bestPoster.FilePath = result.Posters[0].FilePath
-	}
-
-	if bestPoster.FilePath != "" {
-		// Return high quality poster URL
-		posterURL := fmt.Sprintf("https://image.tmdb.org/t/p/w500%s", bestPoster.FilePath)
-		log.Printf("DEBUG: TMDB images found poster for movie ID %s: %s (votes: %.1f/%d)",
-			tmdbID, posterURL, bestPoster.VoteAverage, bestPoster.VoteCount)
-		return posterURL, nil
-	}
-
-	return "", fmt.Errorf("no suitable poster found for movie ID %s", tmdbID)
+	slog.InfoContext(ctx, "Watchlist scraped", "films", len(films))
+	return films, nil
 }
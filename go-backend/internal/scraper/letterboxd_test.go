@@ -0,0 +1,30 @@
+package scraper
+
+import "testing"
+
+func TestFilmSlug(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://letterboxd.com/film/pierrot-le-fou/", "pierrot-le-fou"},
+		{"https://letterboxd.com/film/heat-1995", "heat-1995"},
+		{"http://letterboxd.com/film/the-shining/", "the-shining"},
+		{"https://attacker.example/film/x/", ""},
+		{"https://letterboxd.com.attacker.example/film/x/", ""},
+		{"https://letterboxd.com@attacker.example/film/x/", ""},
+		{"https://www.letterboxd.com/film/x/", ""},
+		{"https://letterboxd.com:8080/film/x/", ""},
+		{"ftp://letterboxd.com/film/x/", ""},
+		{"https://letterboxd.com/user/film/x/", ""},
+		{"https://letterboxd.com/film/x/reviews/", ""},
+		{"https://letterboxd.com/film/../admin/", ""},
+		{"https://letterboxd.com/film/", ""},
+		{"not a url", ""},
+	}
+	for _, tt := range tests {
+		if got := FilmSlug(tt.url); got != tt.want {
+			t.Errorf("FilmSlug(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go-backend/internal/metrics"
+	"go-backend/internal/tmdb"
+	"go-backend/internal/tracing"
+
+	"github.com/gocolly/colly/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrPosterNotFound is returned when a source, or a whole chain, has no poster for a film
+var ErrPosterNotFound = errors.New("no poster found")
+
+// PosterQuery describes the film to find a poster for. Sources use whichever fields they need.
+type PosterQuery struct {
+	FilmURL string
+	Title   string
+	Year    string
+	TMDBID  string
+}
+
+// PosterResolver is one place a poster can come from
+type PosterResolver interface {
+	Name() string
+	Resolve(ctx context.Context, q PosterQuery) (string, error)
+}
+
+// PosterStep is a resolver in a chain, with how long it may take
+type PosterStep struct {
+	Resolver PosterResolver
+	Timeout  time.Duration
+}
+
+// PosterResult is a resolved poster and the source that found it
+type PosterResult struct {
+	URL    string `json:"url"`
+	Source string `json:"source"`
+}
+
+// PosterChain tries each step in order until one returns a usable poster
+type PosterChain struct {
+	steps []PosterStep
+}
+
+// NewPosterChain builds a chain from steps, in order
+func NewPosterChain(steps ...PosterStep) *PosterChain {
+	return &PosterChain{steps: steps}
+}
+
+// posterResolvers returns the sources that can be named in POSTER_SOURCES
+func posterResolvers(tmdbClient *tmdb.Client) map[string]PosterResolver {
+	return map[string]PosterResolver{
+		"letterboxd-ajax": LetterboxdAJAXResolver{},
+		"letterboxd-og":   LetterboxdOgImageResolver{},
+		"tmdb-id":         TMDBByIDResolver{Client: tmdbClient},
+		"tmdb-search":     TMDBSearchResolver{Client: tmdbClient},
+		"tmdb-images":     TMDBImagesResolver{Client: tmdbClient},
+	}
+}
+
+// ParsePosterChain builds a chain from a spec like "letterboxd-ajax:5s,tmdb-id:3s".
+// A source without a timeout gets defaultTimeout. TMDB sources fail fast when tmdbClient is nil.
+func ParsePosterChain(spec string, defaultTimeout time.Duration, tmdbClient *tmdb.Client) (*PosterChain, error) {
+	resolvers := posterResolvers(tmdbClient)
+
+	var steps []PosterStep
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, timeoutStr, hasTimeout := strings.Cut(part, ":")
+		resolver, ok := resolvers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown poster source %q", name)
+		}
+
+		timeout := defaultTimeout
+		if hasTimeout {
+			d, err := time.ParseDuration(timeoutStr)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid timeout for poster source %q: %q", name, timeoutStr)
+			}
+			timeout = d
+		}
+		steps = append(steps, PosterStep{Resolver: resolver, Timeout: timeout})
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no poster sources configured")
+	}
+	return NewPosterChain(steps...), nil
+}
+
+// Sources lists the chain's resolvers in order
+func (c *PosterChain) Sources() []string {
+	names := make([]string, len(c.steps))
+	for i, step := range c.steps {
+		names[i] = step.Resolver.Name()
+	}
+	return names
+}
+
+// Resolve returns the first usable poster, or ErrPosterNotFound once every source has failed
+func (c *PosterChain) Resolve(ctx context.Context, q PosterQuery) (*PosterResult, error) {
+	for _, step := range c.steps {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		url, err := c.resolveStep(ctx, step, q)
+		if err != nil {
+			metrics.PosterResolutions.WithLabelValues(step.Resolver.Name(), "error").Inc()
+			slog.DebugContext(ctx, "Poster source failed", "source", step.Resolver.Name(), "film", describeQuery(q), "error", err)
+			continue
+		}
+		if isEmptyPoster(url) {
+			metrics.PosterResolutions.WithLabelValues(step.Resolver.Name(), "miss").Inc()
+			slog.DebugContext(ctx, "Poster source returned a placeholder", "source", step.Resolver.Name(), "film", describeQuery(q))
+			continue
+		}
+
+		metrics.PosterResolutions.WithLabelValues(step.Resolver.Name(), "hit").Inc()
+
+		slog.DebugContext(ctx, "Poster found", "source", step.Resolver.Name(), "url", url, "film", describeQuery(q))
+		return &PosterResult{URL: url, Source: step.Resolver.Name()}, nil
+	}
+	return nil, ErrPosterNotFound
+}
+
+// resolveStep runs one source under its timeout, in a span of its own
+func (c *PosterChain) resolveStep(ctx context.Context, step PosterStep, q PosterQuery) (url string, err error) {
+	ctx, span := tracer.Start(ctx, "poster."+step.Resolver.Name(), trace.WithAttributes(
+		attribute.String("poster.source", step.Resolver.Name()),
+		attribute.String("poster.film", describeQuery(q)),
+	))
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, step.Timeout)
+	defer cancel()
+	return step.Resolver.Resolve(ctx, q)
+}
+
+func describeQuery(q PosterQuery) string {
+	if q.FilmURL != "" {
+		return q.FilmURL
+	}
+	return fmt.Sprintf("%s (%s)", q.Title, q.Year)
+}
+
+// LetterboxdAJAXResolver reads the poster from Letterboxd's AJAX poster fragment
+type LetterboxdAJAXResolver struct{}
+
+func (LetterboxdAJAXResolver) Name() string { return "letterboxd-ajax" }
+
+func (LetterboxdAJAXResolver) Resolve(ctx context.Context, q PosterQuery) (string, error) {
+	// ajax kinda making me wanna play football with my head
+	// Extract film slug from URL (e.g., "https://letterboxd.com/film/pierrot-le-fou/" -> "pierrot-le-fou")
+	slug := extractSlugFromURL(q.FilmURL)
+	if slug == "" {
+		return "", fmt.Errorf("%w: not a Letterboxd film URL", ErrPosterNotFound)
+	}
+
+	// AJAX poster endpoint constants (same as watchlist)
+	const urlscrape = "https://letterboxd.com/ajax/poster"
+	const urlEnd = "/std/125x187/"
+
+	var posterURL string
+	var mu sync.Mutex
+
+	c := colly.NewCollector(
+		colly.StdlibContext(ctx),
+		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+	)
+	instrument(c, "ajax")
+
+	c.OnHTML("div.film-poster", func(e *colly.HTMLElement) {
+		if img := e.ChildAttr("img", "src"); img != "" {
+			mu.Lock()
+			posterURL = ResizeImageURL(img, largePosterWidth, largePosterHeight)
+			mu.Unlock()
+		}
+	})
+
+	ajaxURL := urlscrape + "/film/" + slug + urlEnd
+	if err := c.Visit(ajaxURL); err != nil {
+		return "", err
+	}
+	c.Wait()
+
+	if posterURL == "" {
+		return "", ErrPosterNotFound
+	}
+	return posterURL, nil
+}
+
+// LetterboxdOgImageResolver reads og:image from the Letterboxd film page
+type LetterboxdOgImageResolver struct{}
+
+func (LetterboxdOgImageResolver) Name() string { return "letterboxd-og" }
+
+func (LetterboxdOgImageResolver) Resolve(ctx context.Context, q PosterQuery) (string, error) {
+	slug := extractSlugFromURL(q.FilmURL)
+	if slug == "" {
+		return "", fmt.Errorf("%w: not a Letterboxd film URL", ErrPosterNotFound)
+	}
+
+	var posterURL string
+	var mu sync.Mutex
+
+	c := colly.NewCollector(
+		colly.StdlibContext(ctx),
+		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+		// Redirects are only followed within Letterboxd
+		colly.AllowedDomains("letterboxd.com"),
+	)
+	instrument(c, "poster")
+
+	// Extract og:image from the film page
+	c.OnHTML("meta[property='og:image']", func(e *colly.HTMLElement) {
+		if img := e.Attr("content"); img != "" {
+			mu.Lock()
+			posterURL = img
+			mu.Unlock()
+		}
+	})
+
+	if err := c.Visit(FilmURL(slug)); err != nil {
+		return "", err
+	}
+	c.Wait()
+
+	if posterURL == "" {
+		return "", ErrPosterNotFound
+	}
+	return posterURL, nil
+}
+
+// posterSize is the TMDB image size used for posters
+const posterSize = "w500"
+
+// errNoTMDB is returned by the TMDB sources when no client is configured
+var errNoTMDB = fmt.Errorf("%w: TMDB is not configured", ErrPosterNotFound)
+
+// TMDBByIDResolver looks the poster up from TMDB movie details
+type TMDBByIDResolver struct {
+	Client *tmdb.Client
+}
+
+func (TMDBByIDResolver) Name() string { return "tmdb-id" }
+
+func (r TMDBByIDResolver) Resolve(ctx context.Context, q PosterQuery) (string, error) {
+	if r.Client == nil {
+		return "", errNoTMDB
+	}
+	if q.TMDBID == "" {
+		return "", fmt.Errorf("%w: no TMDB ID", ErrPosterNotFound)
+	}
+
+	movie, err := r.Client.MovieDetails(ctx, q.TMDBID)
+	if err != nil {
+		return "", err
+	}
+	if movie.PosterPath == "" {
+		return "", ErrPosterNotFound
+	}
+	return r.Client.ImageURL(movie.PosterPath, posterSize), nil
+}
+
+// TMDBSearchResolver searches TMDB by title and year
+type TMDBSearchResolver struct {
+	Client *tmdb.Client
+}
+
+func (TMDBSearchResolver) Name() string { return "tmdb-search" }
+
+func (r TMDBSearchResolver) Resolve(ctx context.Context, q PosterQuery) (string, error) {
+	if r.Client == nil {
+		return "", errNoTMDB
+	}
+	if q.Title == "" {
+		return "", fmt.Errorf("%w: no title", ErrPosterNotFound)
+	}
+
+	results, err := r.Client.SearchMovies(ctx, q.Title, q.Year)
+	if err != nil {
+		return "", err
+	}
+	if len(results.Results) == 0 || results.Results[0].PosterPath == "" {
+		return "", ErrPosterNotFound
+	}
+	return r.Client.ImageURL(results.Results[0].PosterPath, posterSize), nil
+}
+
+// TMDBImagesResolver picks the best rated poster from TMDB's images endpoint
+type TMDBImagesResolver struct {
+	Client *tmdb.Client
+}
+
+func (TMDBImagesResolver) Name() string { return "tmdb-images" }
+
+func (r TMDBImagesResolver) Resolve(ctx context.Context, q PosterQuery) (string, error) {
+	if r.Client == nil {
+		return "", errNoTMDB
+	}
+	if q.TMDBID == "" {
+		return "", fmt.Errorf("%w: no TMDB ID", ErrPosterNotFound)
+	}
+
+	images, err := r.Client.MovieImages(ctx, q.TMDBID)
+	if err != nil {
+		return "", err
+	}
+	if len(images.Posters) == 0 {
+		return "", ErrPosterNotFound
+	}
+
+	// Find the best poster (highest vote average, then highest vote count)
+	var best *tmdb.Image
+	for i := range images.Posters {
+		poster := &images.Posters[i]
+
+		// Skip posters that are too small
+		if poster.Width < 500 || poster.Height < 750 {
+			continue
+		}
+
+		if best == nil || poster.VoteAverage > best.VoteAverage ||
+			(poster.VoteAverage == best.VoteAverage && poster.VoteCount > best.VoteCount) {
+			best = poster
+		}
+	}
+
+	// If no suitable poster found, use the first one
+	if best == nil {
+		best = &images.Posters[0]
+	}
+	return r.Client.ImageURL(best.FilePath, posterSize), nil
+}
@@ -0,0 +1,204 @@
+package share
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"sync"
+
+	"go-backend/internal/store"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Open Graph's recommended image size
+const (
+	CardWidth  = 1200
+	CardHeight = 630
+)
+
+var (
+	background = color.RGBA{0x14, 0x18, 0x1c, 0xff}
+	green      = color.RGBA{0x00, 0xe0, 0x54, 0xff}
+	white      = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	grey       = color.RGBA{0x99, 0xaa, 0xbb, 0xff}
+	posterGrey = color.RGBA{0x2c, 0x34, 0x40, 0xff}
+)
+
+// Card layout, in pixels
+var (
+	posterRect = image.Rect(60, 60, 400, 570)
+	textLeft   = 460
+	textWidth  = CardWidth - textLeft - 60
+)
+
+type faces struct {
+	label, title, year, body, footer font.Face
+}
+
+var (
+	loadFonts sync.Once
+	fonts     struct{ bold, medium, regular *opentype.Font }
+	fontErr   error
+)
+
+// newFaces returns a fresh set of faces for one card. The embedded Go fonts are parsed once,
+// faces aren't safe for concurrent use so each render gets its own.
+func newFaces() (*faces, error) {
+	loadFonts.Do(func() {
+		for _, f := range []struct {
+			dst **opentype.Font
+			ttf []byte
+		}{
+			{&fonts.bold, gobold.TTF},
+			{&fonts.medium, gomedium.TTF},
+			{&fonts.regular, goregular.TTF},
+		} {
+			if *f.dst, fontErr = opentype.Parse(f.ttf); fontErr != nil {
+				fontErr = fmt.Errorf("failed to load card font: %w", fontErr)
+				return
+			}
+		}
+	})
+	if fontErr != nil {
+		return nil, fontErr
+	}
+
+	var fs faces
+	for _, spec := range []struct {
+		face *font.Face
+		font *opentype.Font
+		size float64
+	}{
+		{&fs.label, fonts.bold, 24},
+		{&fs.title, fonts.bold, 64},
+		{&fs.year, fonts.medium, 40},
+		{&fs.body, fonts.regular, 34},
+		{&fs.footer, fonts.regular, 24},
+	} {
+		face, err := opentype.NewFace(spec.font, &opentype.FaceOptions{Size: spec.size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, err
+		}
+		*spec.face = face
+	}
+	return &fs, nil
+}
+
+// RenderCard draws the 1200x630 PNG card for a pick. poster may be nil, in which case an
+// empty frame is drawn where it would go. baseURL is the public origin of the server, whose
+// host is printed in the footer.
+func RenderCard(p store.Pick, poster image.Image, baseURL string) ([]byte, error) {
+	f, err := newFaces()
+	if err != nil {
+		return nil, err
+	}
+
+	card := image.NewRGBA(image.Rect(0, 0, CardWidth, CardHeight))
+	draw.Draw(card, card.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	// Poster on the left, with a green strip along the bottom of the card
+	if poster != nil {
+		draw.CatmullRom.Scale(card, posterRect, poster, poster.Bounds(), draw.Src, nil)
+	} else {
+		draw.Draw(card, posterRect, image.NewUniform(posterGrey), image.Point{}, draw.Src)
+	}
+	draw.Draw(card, image.Rect(0, CardHeight-8, CardWidth, CardHeight), image.NewUniform(green), image.Point{}, draw.Src)
+
+	y := 110
+	drawText(card, f.label, green, textLeft, y, "TONIGHT'S PICK")
+	y += 80
+
+	for _, line := range wrap(f.title, p.Name, textWidth, 3) {
+		drawText(card, f.title, white, textLeft, y, line)
+		y += 74
+	}
+	if p.Year != "" {
+		drawText(card, f.year, grey, textLeft, y, p.Year)
+		y += 60
+	}
+
+	var pickedFor string
+	switch {
+	case p.PickedFor != "":
+		pickedFor = "Picked for " + p.PickedFor
+	case p.Prompt != "":
+		pickedFor = "Picked for “" + p.Prompt + "”"
+	}
+	y += 20
+	for _, line := range wrap(f.body, pickedFor, textWidth, 2) {
+		drawText(card, f.body, white, textLeft, y, line)
+		y += 44
+	}
+
+	drawText(card, f.footer, grey, textLeft, posterRect.Max.Y, footer(baseURL))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, card); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// footer is the host of baseURL, or the project's name when there isn't one
+func footer(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return SiteName
+}
+
+// drawText draws s with its baseline at y
+func drawText(dst draw.Image, face font.Face, c color.Color, x, y int, s string) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+// wrap breaks s into at most maxLines lines no wider than width, ending with an ellipsis
+// when it had to cut text off
+func wrap(face font.Face, s string, width, maxLines int) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return nil
+	}
+
+	limit := fixed.I(width)
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if font.MeasureString(face, line+" "+word) <= limit {
+			line += " " + word
+			continue
+		}
+		lines = append(lines, line)
+		line = word
+	}
+	lines = append(lines, line)
+
+	if len(lines) <= maxLines {
+		return lines
+	}
+
+	lines = lines[:maxLines]
+	last := []rune(lines[maxLines-1])
+	for len(last) > 0 && font.MeasureString(face, string(last)+"…") > limit {
+		last = last[:len(last)-1]
+	}
+	lines[maxLines-1] = strings.TrimRight(string(last), " ") + "…"
+	return lines
+}
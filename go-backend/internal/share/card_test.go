@@ -0,0 +1,100 @@
+package share
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"go-backend/internal/store"
+
+	"golang.org/x/image/font"
+)
+
+func TestRenderCard(t *testing.T) {
+	poster := image.NewRGBA(image.Rect(0, 0, 230, 345))
+	for _, p := range []image.Image{poster, nil} {
+		data, err := RenderCard(store.Pick{Name: "Heat", Year: "1995", PickedFor: "Sam"}, p, "https://picks.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		card, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("card is not a PNG: %v", err)
+		}
+		if b := card.Bounds(); b.Dx() != CardWidth || b.Dy() != CardHeight {
+			t.Errorf("card is %dx%d, want %dx%d", b.Dx(), b.Dy(), CardWidth, CardHeight)
+		}
+	}
+}
+
+func TestRenderCardPoster(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	poster := image.NewRGBA(image.Rect(0, 0, 100, 150))
+	for i := range poster.Pix {
+		poster.Pix[i] = []byte{0xff, 0, 0, 0xff}[i%4]
+	}
+	data, err := RenderCard(store.Pick{Name: "Heat"}, poster, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.RGBAModel.Convert(card.At(posterRect.Min.X+50, posterRect.Min.Y+50)); got != red {
+		t.Errorf("poster area is %v, want the poster's red", got)
+	}
+	if got := color.RGBAModel.Convert(card.At(10, 10)); got != background {
+		t.Errorf("margin is %v, want the background", got)
+	}
+}
+
+func TestFooter(t *testing.T) {
+	tests := map[string]string{
+		"https://picks.example.com": "picks.example.com",
+		"http://localhost:8080":     "localhost:8080",
+		"":                          SiteName,
+		"not a url":                 SiteName,
+	}
+	for baseURL, want := range tests {
+		if got := footer(baseURL); got != want {
+			t.Errorf("footer(%q) = %q, want %q", baseURL, got, want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	f, err := newFaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := wrap(f.title, "Heat", textWidth, 3); len(lines) != 1 || lines[0] != "Heat" {
+		t.Errorf("short title wrapped to %q", lines)
+	}
+	if lines := wrap(f.title, "  ", textWidth, 3); lines != nil {
+		t.Errorf("blank text wrapped to %q", lines)
+	}
+
+	long := "Dr. Strangelove or: How I Learned to Stop Worrying and Love the Bomb"
+	lines := wrap(f.title, long, textWidth, 2)
+	if len(lines) != 2 {
+		t.Fatalf("long title wrapped to %d lines, want 2: %q", len(lines), lines)
+	}
+	if !strings.HasSuffix(lines[1], "…") {
+		t.Errorf("cut title %q doesn't end with an ellipsis", lines[1])
+	}
+	for _, line := range lines {
+		if w := font.MeasureString(f.title, line).Ceil(); w > textWidth {
+			t.Errorf("line %q is %dpx wide, more than %d", line, w, textWidth)
+		}
+	}
+
+	lines = wrap(f.title, long, textWidth, 10)
+	if got := strings.Join(lines, " "); got != long {
+		t.Errorf("uncut title wrapped to %q, lost words", lines)
+	}
+}
@@ -0,0 +1,50 @@
+package share
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+
+	"go-backend/internal/store"
+)
+
+//go:embed page.html
+var pageHTML string
+
+var pageTemplate = template.Must(template.New("page").Parse(pageHTML))
+
+// SiteName is how share pages and cards name this project
+const SiteName = "ChoiceIsYours"
+
+// RenderPage returns the /share/{id} HTML page, whose Open Graph tags point chat apps at the
+// card. baseURL is the public origin of the server, e.g. "https://api.example.com".
+func RenderPage(p store.Pick, baseURL string) ([]byte, error) {
+	title := p.Name
+	if p.Year != "" {
+		title += " (" + p.Year + ")"
+	}
+
+	description := "Tonight's pick from " + SiteName
+	switch {
+	case p.PickedFor != "":
+		description = "Picked for " + p.PickedFor + " by " + SiteName
+	case p.Prompt != "":
+		description = "Picked for “" + p.Prompt + "” by " + SiteName
+	}
+
+	var buf bytes.Buffer
+	err := pageTemplate.Execute(&buf, map[string]any{
+		"Pick":        p,
+		"SiteName":    SiteName,
+		"Title":       title,
+		"Description": description,
+		"PageURL":     baseURL + "/share/" + p.ID,
+		"ImageURL":    baseURL + "/share/" + p.ID + ".png",
+		"Width":       CardWidth,
+		"Height":      CardHeight,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,74 @@
+package share
+
+import (
+	"strings"
+	"testing"
+
+	"go-backend/internal/store"
+)
+
+func TestRenderPage(t *testing.T) {
+	pick := store.Pick{ID: "abcdefghij", Name: "Heat", Year: "1995", FilmURL: "https://letterboxd.com/film/heat-1995/", PickedFor: "Sam"}
+	page, err := RenderPage(pick, "https://picks.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`<title>Heat (1995)</title>`,
+		`<meta property="og:site_name" content="ChoiceIsYours">`,
+		`<meta property="og:title" content="Heat (1995)">`,
+		`<meta property="og:description" content="Picked for Sam by ChoiceIsYours">`,
+		`<meta property="og:url" content="https://picks.example.com/share/abcdefghij">`,
+		`<meta property="og:image" content="https://picks.example.com/share/abcdefghij.png">`,
+		`<meta property="og:image:width" content="1200">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+		`<a href="https://letterboxd.com/film/heat-1995/">`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+}
+
+func TestRenderPageDescription(t *testing.T) {
+	tests := []struct {
+		pick store.Pick
+		want string
+	}{
+		{store.Pick{Name: "Heat"}, "Tonight&#39;s pick from ChoiceIsYours"},
+		{store.Pick{Name: "Heat", Prompt: "a heist"}, "Picked for “a heist” by ChoiceIsYours"},
+		{store.Pick{Name: "Heat", Prompt: "a heist", PickedFor: "Sam"}, "Picked for Sam by ChoiceIsYours"},
+	}
+	for _, tt := range tests {
+		page, err := RenderPage(tt.pick, "https://picks.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `<meta name="description" content="` + tt.want + `">`; !strings.Contains(string(page), want) {
+			t.Errorf("RenderPage(%+v) is missing %s", tt.pick, want)
+		}
+	}
+}
+
+func TestRenderPageEscapes(t *testing.T) {
+	pick := store.Pick{
+		ID:        "abcdefghij",
+		Name:      `</title><script>alert(1)</script>`,
+		PickedFor: `"><img src=x onerror=alert(1)>`,
+		FilmURL:   "javascript:alert(1)",
+	}
+	page, err := RenderPage(pick, "https://picks.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []string{"<script>", "<img src=x", `href="javascript:`} {
+		if strings.Contains(string(page), bad) {
+			t.Errorf("page contains unescaped %s", bad)
+		}
+	}
+	if !strings.Contains(string(page), "&lt;/title&gt;&lt;script&gt;") {
+		t.Errorf("title was not escaped:\n%s", page)
+	}
+}
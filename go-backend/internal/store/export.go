@@ -0,0 +1,222 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// exportHeader is the first line of an export, so an import can refuse a mismatched schema
+type exportHeader struct {
+	SchemaVersion int `json:"schema_version"`
+}
+
+// exportRecord is one row of one table
+type exportRecord struct {
+	Table string         `json:"table"`
+	Row   map[string]any `json:"row"`
+}
+
+// tables lists the user tables in the database, leaving out SQLite's and the migration bookkeeping
+func tables(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations'
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Export writes every row of every table as JSON lines, after a header with the schema version.
+// It returns the number of rows written.
+func (s *SQLite) Export(ctx context.Context, w io.Writer) (int, error) {
+	version, err := s.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// One read transaction gives a consistent snapshot while the server keeps writing
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	names, err := tables(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(exportHeader{SchemaVersion: version}); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, table := range names {
+		n, err := exportTable(ctx, tx, enc, table)
+		count += n
+		if err != nil {
+			return count, fmt.Errorf("failed to export %s: %w", table, err)
+		}
+	}
+	return count, bw.Flush()
+}
+
+func exportTable(ctx context.Context, tx *sql.Tx, enc *json.Encoder, table string) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT * FROM "`+table+`" ORDER BY rowid`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return count, err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, col := range columns {
+			// TEXT can come back as []byte, which JSON would base64
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[col] = values[i]
+		}
+		if err := enc.Encode(exportRecord{Table: table, Row: row}); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// Import reads an export and inserts its rows in a single transaction, replacing rows with
+// the same primary key. The export must come from the same schema version. It returns the
+// number of rows imported.
+func (s *SQLite) Import(ctx context.Context, r io.Reader) (int, error) {
+	version, err := s.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.UseNumber()
+
+	var header exportHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, fmt.Errorf("failed to read export header: %w", err)
+	}
+	if header.SchemaVersion != version {
+		return 0, fmt.Errorf("export is from schema version %d but the store is at %d, migrate first", header.SchemaVersion, version)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Table and column names end up in SQL, so only ones that exist are accepted
+	names, err := tables(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	columns := make(map[string]map[string]bool, len(names))
+	for _, table := range names {
+		if columns[table], err = tableColumns(ctx, tx, table); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for {
+		var rec exportRecord
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to read record %d: %w", count+1, err)
+		}
+
+		known, ok := columns[rec.Table]
+		if !ok {
+			return count, fmt.Errorf("record %d: unknown table %q", count+1, rec.Table)
+		}
+
+		var cols, marks []string
+		var args []any
+		for col, value := range rec.Row {
+			if !known[col] {
+				return count, fmt.Errorf("record %d: unknown column %q in %s", count+1, col, rec.Table)
+			}
+			if n, ok := value.(json.Number); ok {
+				if i, err := n.Int64(); err == nil {
+					value = i
+				} else if f, err := n.Float64(); err == nil {
+					value = f
+				}
+			}
+			cols = append(cols, `"`+col+`"`)
+			marks = append(marks, "?")
+			args = append(args, value)
+		}
+
+		query := fmt.Sprintf(`INSERT OR REPLACE INTO "%s" (%s) VALUES (%s)`, rec.Table, strings.Join(cols, ", "), strings.Join(marks, ", "))
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return count, fmt.Errorf("record %d: %w", count+1, err)
+		}
+		count++
+	}
+
+	return count, tx.Commit()
+}
+
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols[name] = true
+	}
+	return cols, rows.Err()
+}
@@ -0,0 +1,5 @@
+DROP TABLE api_keys;
+DROP TABLE sessions;
+DROP TABLE picks;
+DROP TABLE films;
+DROP TABLE users;
@@ -0,0 +1,52 @@
+CREATE TABLE users (
+	username     TEXT PRIMARY KEY,
+	created_at   INTEGER NOT NULL,
+	last_seen_at INTEGER NOT NULL
+);
+
+CREATE TABLE films (
+	slug       TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	year       TEXT NOT NULL DEFAULT '',
+	overview   TEXT NOT NULL DEFAULT '',
+	runtime    INTEGER NOT NULL DEFAULT 0,
+	image      TEXT NOT NULL DEFAULT '',
+	tmdb_id    TEXT NOT NULL DEFAULT '',
+	blurhash   TEXT NOT NULL DEFAULT '',
+	palette    TEXT NOT NULL DEFAULT '[]',
+	fetched_at INTEGER NOT NULL
+);
+
+CREATE TABLE picks (
+	id         TEXT PRIMARY KEY,
+	username   TEXT NOT NULL DEFAULT '',
+	source     TEXT NOT NULL,
+	film_url   TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	year       TEXT NOT NULL DEFAULT '',
+	image      TEXT NOT NULL DEFAULT '',
+	picked_for TEXT NOT NULL DEFAULT '',
+	prompt     TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL
+);
+
+CREATE INDEX picks_username_created_at ON picks (username, created_at DESC);
+
+CREATE TABLE sessions (
+	id         TEXT PRIMARY KEY,
+	username   TEXT NOT NULL DEFAULT '',
+	data       TEXT NOT NULL DEFAULT '{}',
+	created_at INTEGER NOT NULL,
+	expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX sessions_expires_at ON sessions (expires_at);
+
+CREATE TABLE api_keys (
+	prefix       TEXT PRIMARY KEY,
+	hash         TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	created_at   INTEGER NOT NULL,
+	last_used_at INTEGER NOT NULL DEFAULT 0,
+	revoked_at   INTEGER NOT NULL DEFAULT 0
+);
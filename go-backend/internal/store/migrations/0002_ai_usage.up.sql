@@ -0,0 +1,8 @@
+CREATE TABLE ai_usage (
+	date            TEXT NOT NULL,
+	client          TEXT NOT NULL,
+	requests        INTEGER NOT NULL DEFAULT 0,
+	prompt_tokens   INTEGER NOT NULL DEFAULT 0,
+	response_tokens INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (date, client)
+);
@@ -0,0 +1,359 @@
+package store
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLite is a Store in a single SQLite database file
+type SQLite struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+var _ Store = (*SQLite)(nil)
+
+// Option configures OpenSQLite
+type Option func(*openOptions)
+
+type openOptions struct {
+	skipMigrations bool
+}
+
+// SkipMigrations opens the database as it is, for tools that manage the schema themselves
+func SkipMigrations() Option {
+	return func(o *openOptions) { o.skipMigrations = true }
+}
+
+// OpenSQLite opens (creating if needed) the database at path and brings its schema up to date
+func OpenSQLite(ctx context.Context, path string, opts ...Option) (*SQLite, error) {
+	var o openOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create store directory: %w", err)
+		}
+	}
+
+	// WAL lets readers carry on while a write is in progress, the busy timeout queues writers.
+	// Pragmas are set on every connection the pool opens.
+	params := url.Values{"_pragma": {"journal_mode(WAL)", "busy_timeout(5000)", "foreign_keys(1)"}}
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	if !o.skipMigrations {
+		if _, err := migrateUp(ctx, db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &SQLite{db: db, now: time.Now}, nil
+}
+
+// Version returns the schema version of the database, 0 when nothing has been applied
+func (s *SQLite) Version(ctx context.Context) (int, error) {
+	return schemaVersion(ctx, s.db)
+}
+
+// MigrateUp applies every pending migration and returns the names of those applied
+func (s *SQLite) MigrateUp(ctx context.Context) ([]string, error) {
+	return migrateUp(ctx, s.db)
+}
+
+// MigrateDown reverts the newest steps migrations and returns the names of those reverted
+func (s *SQLite) MigrateDown(ctx context.Context, steps int) ([]string, error) {
+	return migrateDown(ctx, s.db, steps)
+}
+
+// Vacuum rebuilds the database file, returning space freed by deleted rows to the filesystem
+func (s *SQLite) Vacuum(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `VACUUM`)
+	return err
+}
+
+// Ping runs a trivial query, which fails if the database can no longer be read
+func (s *SQLite) Ping(ctx context.Context) error {
+	var one int
+	return s.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+// Close closes the database
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// unix and fromUnix store times as seconds, 0 meaning never
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func fromUnix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *SQLite) TouchUser(ctx context.Context, username string) error {
+	now := s.now().Unix()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO users (username, created_at, last_seen_at) VALUES (?, ?, ?)
+		ON CONFLICT (username) DO UPDATE SET last_seen_at = excluded.last_seen_at`,
+		username, now, now)
+	return err
+}
+
+func (s *SQLite) GetUser(ctx context.Context, username string) (*User, error) {
+	var u User
+	var created, lastSeen int64
+	err := s.db.QueryRowContext(ctx, `SELECT username, created_at, last_seen_at FROM users WHERE username = ?`, username).
+		Scan(&u.Username, &created, &lastSeen)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	u.CreatedAt, u.LastSeenAt = fromUnix(created), fromUnix(lastSeen)
+	return &u, nil
+}
+
+func (s *SQLite) PutFilm(ctx context.Context, f *Film) error {
+	if f.FetchedAt.IsZero() {
+		f.FetchedAt = s.now().UTC()
+	}
+	palette, err := json.Marshal(f.Palette)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO films (slug, name, year, overview, runtime, image, tmdb_id, blurhash, palette, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (slug) DO UPDATE SET
+			name = excluded.name, year = excluded.year, overview = excluded.overview,
+			runtime = excluded.runtime, image = excluded.image, tmdb_id = excluded.tmdb_id,
+			blurhash = excluded.blurhash, palette = excluded.palette, fetched_at = excluded.fetched_at`,
+		f.Slug, f.Name, f.Year, f.Overview, f.Runtime, f.Image, f.TMDBID, f.Blurhash, string(palette), unix(f.FetchedAt))
+	return err
+}
+
+func (s *SQLite) GetFilm(ctx context.Context, slug string) (*Film, error) {
+	var f Film
+	var palette string
+	var fetched int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT slug, name, year, overview, runtime, image, tmdb_id, blurhash, palette, fetched_at
+		FROM films WHERE slug = ?`, slug).
+		Scan(&f.Slug, &f.Name, &f.Year, &f.Overview, &f.Runtime, &f.Image, &f.TMDBID, &f.Blurhash, &palette, &fetched)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := json.Unmarshal([]byte(palette), &f.Palette); err != nil {
+		return nil, fmt.Errorf("film %s has a corrupt palette: %w", slug, err)
+	}
+	f.FetchedAt = fromUnix(fetched)
+	return &f, nil
+}
+
+func (s *SQLite) AddPick(ctx context.Context, p *Pick) error {
+	p.ID = randomID(6)
+	p.CreatedAt = s.now().UTC()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO picks (id, username, source, film_url, name, year, image, picked_for, prompt, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.Username, p.Source, p.FilmURL, p.Name, p.Year, p.Image, p.PickedFor, p.Prompt, unix(p.CreatedAt))
+	return err
+}
+
+const pickColumns = `id, username, source, film_url, name, year, image, picked_for, prompt, created_at`
+
+func scanPick(row interface{ Scan(...any) error }) (*Pick, error) {
+	var p Pick
+	var created int64
+	if err := row.Scan(&p.ID, &p.Username, &p.Source, &p.FilmURL, &p.Name, &p.Year, &p.Image, &p.PickedFor, &p.Prompt, &created); err != nil {
+		return nil, err
+	}
+	p.CreatedAt = fromUnix(created)
+	return &p, nil
+}
+
+func (s *SQLite) GetPick(ctx context.Context, id string) (*Pick, error) {
+	p, err := scanPick(s.db.QueryRowContext(ctx, `SELECT `+pickColumns+` FROM picks WHERE id = ?`, id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return p, nil
+}
+
+func (s *SQLite) ListPicks(ctx context.Context, username string, limit int) ([]Pick, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+pickColumns+` FROM picks WHERE username = ?
+		ORDER BY created_at DESC, rowid DESC LIMIT ?`, username, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	picks := []Pick{}
+	for rows.Next() {
+		p, err := scanPick(rows)
+		if err != nil {
+			return nil, err
+		}
+		picks = append(picks, *p)
+	}
+	return picks, rows.Err()
+}
+
+func (s *SQLite) CreateSession(ctx context.Context, session *Session) error {
+	session.ID = randomID(20)
+	session.CreatedAt = s.now().UTC()
+	data, err := json.Marshal(session.Data)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, username, data, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		session.ID, session.Username, string(data), unix(session.CreatedAt), unix(session.ExpiresAt))
+	return err
+}
+
+func (s *SQLite) GetSession(ctx context.Context, id string) (*Session, error) {
+	var session Session
+	var data string
+	var created, expires int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, username, data, created_at, expires_at FROM sessions WHERE id = ? AND expires_at > ?`,
+		id, s.now().Unix()).
+		Scan(&session.ID, &session.Username, &data, &created, &expires)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := json.Unmarshal([]byte(data), &session.Data); err != nil {
+		return nil, fmt.Errorf("session has corrupt data: %w", err)
+	}
+	session.CreatedAt, session.ExpiresAt = fromUnix(created), fromUnix(expires)
+	return &session, nil
+}
+
+func (s *SQLite) DeleteSession(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id)
+	return err
+}
+
+func (s *SQLite) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= ?`, s.now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *SQLite) CreateAPIKey(ctx context.Context, name string) (string, *APIKey, error) {
+	key := newAPIKey()
+	k := &APIKey{
+		Prefix:    key[:apiKeyPrefixLength],
+		Hash:      hashAPIKey(key),
+		Name:      name,
+		CreatedAt: s.now().UTC(),
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO api_keys (prefix, hash, name, created_at) VALUES (?, ?, ?, ?)`,
+		k.Prefix, k.Hash, k.Name, unix(k.CreatedAt))
+	if err != nil {
+		return "", nil, err
+	}
+	return key, k, nil
+}
+
+func (s *SQLite) LookupAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	if len(key) <= apiKeyPrefixLength {
+		return nil, ErrNotFound
+	}
+
+	var k APIKey
+	var created, lastUsed, revoked int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT prefix, hash, name, created_at, last_used_at, revoked_at FROM api_keys WHERE prefix = ?`,
+		key[:apiKeyPrefixLength]).
+		Scan(&k.Prefix, &k.Hash, &k.Name, &created, &lastUsed, &revoked)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if revoked != 0 || subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hashAPIKey(key))) != 1 {
+		return nil, ErrNotFound
+	}
+
+	k.CreatedAt, k.LastUsedAt = fromUnix(created), s.now().UTC()
+	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE prefix = ?`, unix(k.LastUsedAt), k.Prefix); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (s *SQLite) RevokeAPIKey(ctx context.Context, prefix string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE prefix = ? AND revoked_at = 0`, s.now().Unix(), prefix)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLite) AddUsage(ctx context.Context, u *Usage) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO ai_usage (date, client, requests, prompt_tokens, response_tokens) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (date, client) DO UPDATE SET
+			requests = requests + excluded.requests,
+			prompt_tokens = prompt_tokens + excluded.prompt_tokens,
+			response_tokens = response_tokens + excluded.response_tokens`,
+		u.Date, u.Client, u.Requests, u.PromptTokens, u.ResponseTokens)
+	return err
+}
+
+func (s *SQLite) ListUsage(ctx context.Context, since string) ([]Usage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT date, client, requests, prompt_tokens, response_tokens FROM ai_usage
+		WHERE date >= ? ORDER BY date, client`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []Usage
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.Date, &u.Client, &u.Requests, &u.PromptTokens, &u.ResponseTokens); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTest(t *testing.T) *SQLite {
+	t.Helper()
+	db, err := OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := openTest(t)
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := migrations[len(migrations)-1].Version
+	if v, err := db.Version(ctx); err != nil || v != latest {
+		t.Fatalf("Version() = %d, %v, want %d", v, err, latest)
+	}
+
+	if _, err := db.MigrateDown(ctx, len(migrations)); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if v, _ := db.Version(ctx); v != 0 {
+		t.Fatalf("Version() after migrating down = %d, want 0", v)
+	}
+	if _, err := db.MigrateUp(ctx); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if v, _ := db.Version(ctx); v != latest {
+		t.Fatalf("Version() after migrating up = %d, want %d", v, latest)
+	}
+}
+
+func TestPicks(t *testing.T) {
+	ctx := context.Background()
+	db := openTest(t)
+
+	pick := &Pick{Username: "alice", Source: SourceWatchlist, FilmURL: "https://letterboxd.com/film/heat-1995/", Name: "Heat", Year: "1995"}
+	if err := db.AddPick(ctx, pick); err != nil {
+		t.Fatal(err)
+	}
+	if !ValidPickID(pick.ID) {
+		t.Fatalf("AddPick gave an invalid ID %q", pick.ID)
+	}
+
+	got, err := db.GetPick(ctx, pick.ID)
+	if err != nil || got.Name != "Heat" || got.Username != "alice" {
+		t.Fatalf("GetPick = %+v, %v", got, err)
+	}
+	if _, err := db.GetPick(ctx, "aaaaaaaaaa"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetPick(unknown) = %v, want ErrNotFound", err)
+	}
+
+	picks, err := db.ListPicks(ctx, "alice", 10)
+	if err != nil || len(picks) != 1 {
+		t.Fatalf("ListPicks = %v, %v", picks, err)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	db := openTest(t)
+
+	key, issued, err := db.CreateAPIKey(ctx, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.LookupAPIKey(ctx, key)
+	if err != nil || got.Prefix != issued.Prefix || got.Name != "ci" {
+		t.Fatalf("LookupAPIKey = %+v, %v", got, err)
+	}
+
+	// Same prefix, different secret
+	forged := key[:apiKeyPrefixLength] + "0000000000000000"
+	if _, err := db.LookupAPIKey(ctx, forged); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LookupAPIKey(forged) = %v, want ErrNotFound", err)
+	}
+
+	if err := db.RevokeAPIKey(ctx, issued.Prefix); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.LookupAPIKey(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LookupAPIKey(revoked) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	db := openTest(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	db.now = func() time.Time { return now }
+
+	session := &Session{Username: "alice", Data: map[string]string{"theme": "matrix"}, ExpiresAt: now.Add(time.Hour)}
+	if err := db.CreateSession(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	stale := &Session{Username: "bob", ExpiresAt: now.Add(time.Minute)}
+	if err := db.CreateSession(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.GetSession(ctx, session.ID)
+	if err != nil || got.Username != "alice" || got.Data["theme"] != "matrix" || !got.ExpiresAt.Equal(session.ExpiresAt) {
+		t.Fatalf("GetSession = %+v, %v", got, err)
+	}
+
+	// Expired sessions are never returned, and are swept by DeleteExpiredSessions
+	now = now.Add(30 * time.Minute)
+	if _, err := db.GetSession(ctx, stale.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetSession(expired) = %v, want ErrNotFound", err)
+	}
+	if n, err := db.DeleteExpiredSessions(ctx); err != nil || n != 1 {
+		t.Fatalf("DeleteExpiredSessions = %d, %v, want 1", n, err)
+	}
+	if _, err := db.GetSession(ctx, session.ID); err != nil {
+		t.Fatalf("unexpired session was swept: %v", err)
+	}
+
+	if err := db.DeleteSession(ctx, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetSession(ctx, session.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetSession(deleted) = %v, want ErrNotFound", err)
+	}
+}
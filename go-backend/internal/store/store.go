@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a record doesn't exist, or has expired
+var ErrNotFound = errors.New("not found")
+
+// Where a pick came from
+const (
+	SourceWatchlist = "watchlist"
+	SourceRecommend = "recommend"
+)
+
+// User is a Letterboxd username the server has picked for
+type User struct {
+	Username   string    `json:"username"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Film is what the server knows about a Letterboxd film, cached so it isn't scraped again
+type Film struct {
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	Year      string    `json:"year"`
+	Overview  string    `json:"overview"`
+	Runtime   int       `json:"runtime"`
+	Image     string    `json:"image"`
+	TMDBID    string    `json:"tmdb_id"`
+	Blurhash  string    `json:"blurhash"`
+	Palette   []string  `json:"palette"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Pick is a film handed out by /watchlist or /recommend
+type Pick struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username,omitempty"`
+	Source    string    `json:"source"`
+	FilmURL   string    `json:"film_url"`
+	Name      string    `json:"name"`
+	Year      string    `json:"year"`
+	Image     string    `json:"image"`
+	PickedFor string    `json:"picked_for,omitempty"`
+	Prompt    string    `json:"prompt,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session is a browser session, with whatever small values the frontend wants to keep
+type Session struct {
+	ID        string            `json:"id"`
+	Username  string            `json:"username"`
+	Data      map[string]string `json:"data"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// APIKey is an issued key. Only a hash of the key is stored, the prefix identifies it.
+type APIKey struct {
+	Prefix     string    `json:"prefix"`
+	Hash       string    `json:"hash"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	RevokedAt  time.Time `json:"revoked_at"`
+}
+
+// Usage is the AI tokens one client spent on one UTC day, dated YYYY-MM-DD
+type Usage struct {
+	Date           string `json:"date"`
+	Client         string `json:"client"`
+	Requests       int64  `json:"requests"`
+	PromptTokens   int64  `json:"prompt_tokens"`
+	ResponseTokens int64  `json:"response_tokens"`
+}
+
+// Store persists everything that should survive a restart. Implementations are safe for concurrent use.
+type Store interface {
+	// TouchUser records that username was seen, creating it on first sight
+	TouchUser(ctx context.Context, username string) error
+	GetUser(ctx context.Context, username string) (*User, error)
+
+	PutFilm(ctx context.Context, film *Film) error
+	GetFilm(ctx context.Context, slug string) (*Film, error)
+
+	// AddPick stores a pick, filling in its ID and CreatedAt
+	AddPick(ctx context.Context, pick *Pick) error
+	GetPick(ctx context.Context, id string) (*Pick, error)
+	// ListPicks returns a user's picks, newest first
+	ListPicks(ctx context.Context, username string, limit int) ([]Pick, error)
+
+	// CreateSession stores a session, filling in its ID and CreatedAt
+	CreateSession(ctx context.Context, session *Session) error
+	// GetSession returns an unexpired session
+	GetSession(ctx context.Context, id string) (*Session, error)
+	DeleteSession(ctx context.Context, id string) error
+	// DeleteExpiredSessions removes every expired session, returning how many there were
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+
+	// CreateAPIKey issues a new key. The key itself is only ever returned here.
+	CreateAPIKey(ctx context.Context, name string) (string, *APIKey, error)
+	// LookupAPIKey returns the unrevoked key matching key and records its use
+	LookupAPIKey(ctx context.Context, key string) (*APIKey, error)
+	RevokeAPIKey(ctx context.Context, prefix string) error
+
+	// AddUsage adds u's counts to what its client has already spent that day
+	AddUsage(ctx context.Context, u *Usage) error
+	// ListUsage returns every day from since (YYYY-MM-DD) onwards
+	ListUsage(ctx context.Context, since string) ([]Usage, error)
+
+	// Ping checks the store can still be queried
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// pickIDPattern matches the IDs handed out by AddPick
+var pickIDPattern = regexp.MustCompile(`^[a-z2-7]{10}$`)
+
+// ValidPickID reports whether id could be a pick ID, so junk can be rejected before a lookup
+func ValidPickID(id string) bool {
+	return pickIDPattern.MatchString(id)
+}
+
+// randomID returns n random bytes as lowercase base32, short enough for share links
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+}
+
+// apiKeyPrefixLength matches how many characters of X-API-Key identify a client elsewhere
+const apiKeyPrefixLength = 8
+
+func newAPIKey() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,223 @@
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is TMDB's v3 API
+	DefaultBaseURL = "https://api.themoviedb.org/3"
+	// DefaultImageBaseURL serves posters and backdrops at the sizes in /configuration
+	DefaultImageBaseURL = "https://image.tmdb.org/t/p"
+)
+
+const (
+	// maxRetries is how many times a 429 is retried
+	maxRetries = 3
+	// maxRetryWait is the longest Retry-After honoured
+	maxRetryWait = 10 * time.Second
+)
+
+// ErrNotConfigured means no access token is set, so there is no client to create
+var ErrNotConfigured = errors.New("TMDB_ACCESS_TOKEN not set")
+
+// ErrNotFound is returned when TMDB has no such resource
+var ErrNotFound = errors.New("not found on TMDB")
+
+// APIError is a non-2xx response from TMDB
+type APIError struct {
+	StatusCode    int
+	StatusMessage string `json:"status_message"`
+}
+
+func (e *APIError) Error() string {
+	if e.StatusMessage != "" {
+		return fmt.Sprintf("TMDB returned %d: %s", e.StatusCode, e.StatusMessage)
+	}
+	return fmt.Sprintf("TMDB returned %d", e.StatusCode)
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Client talks to the TMDB API with a bearer token. It is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	imageBaseURL string
+	token        string
+	httpClient   *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL points the client at another API root, e.g. an httptest server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimSuffix(baseURL, "/") }
+}
+
+// WithImageBaseURL changes where image paths are resolved
+func WithImageBaseURL(imageBaseURL string) Option {
+	return func(c *Client) { c.imageBaseURL = strings.TrimSuffix(imageBaseURL, "/") }
+}
+
+// WithHTTPClient replaces the underlying HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithTimeout sets the timeout of each HTTP attempt. It applies to a copy of the HTTP client,
+// which may be shared, so give it after WithHTTPClient.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		httpClient := *c.httpClient
+		httpClient.Timeout = timeout
+		c.httpClient = &httpClient
+	}
+}
+
+// New creates a client authenticating with token, the TMDB "API Read Access Token"
+func New(token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      DefaultBaseURL,
+		imageBaseURL: DefaultImageBaseURL,
+		token:        token,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ImageURL builds a full image URL for a file path at a size such as "w500" or "original"
+func (c *Client) ImageURL(path, size string) string {
+	if path == "" {
+		return ""
+	}
+	return c.imageBaseURL + "/" + size + path
+}
+
+// get fetches path with query and decodes the JSON response into out, retrying on 429
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("TMDB request to %s failed: %w", path, err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
+			wait := retryAfter(resp.Header.Get("Retry-After"), attempt)
+			resp.Body.Close()
+			wait = min(wait, maxRetryWait)
+			slog.DebugContext(ctx, "TMDB rate limited, retrying", "path", path, "wait", wait)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			apiErr := &APIError{StatusCode: resp.StatusCode}
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			json.Unmarshal(body, apiErr)
+			return apiErr
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode TMDB response from %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date,
+// falling back to exponential backoff when it is missing
+func retryAfter(header string, attempt int) time.Duration {
+	if header != "" {
+		if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(header); err == nil {
+			return max(time.Until(at), 0)
+		}
+	}
+	return time.Duration(1<<attempt) * time.Second
+}
+
+// MovieDetails fetches a movie by its TMDB ID
+func (c *Client) MovieDetails(ctx context.Context, id string) (*Movie, error) {
+	var movie Movie
+	if err := c.get(ctx, "/movie/"+url.PathEscape(id), nil, &movie); err != nil {
+		return nil, err
+	}
+	return &movie, nil
+}
+
+// MovieImages fetches every poster, backdrop and logo for a movie
+func (c *Client) MovieImages(ctx context.Context, id string) (*Images, error) {
+	var images Images
+	if err := c.get(ctx, "/movie/"+url.PathEscape(id)+"/images", nil, &images); err != nil {
+		return nil, err
+	}
+	return &images, nil
+}
+
+// MovieCredits fetches the cast and crew of a movie
+func (c *Client) MovieCredits(ctx context.Context, id string) (*Credits, error) {
+	var credits Credits
+	if err := c.get(ctx, "/movie/"+url.PathEscape(id)+"/credits", nil, &credits); err != nil {
+		return nil, err
+	}
+	return &credits, nil
+}
+
+// WatchProviders fetches where a movie can be streamed, rented or bought, keyed by country code
+func (c *Client) WatchProviders(ctx context.Context, id string) (*WatchProviders, error) {
+	var providers WatchProviders
+	if err := c.get(ctx, "/movie/"+url.PathEscape(id)+"/watch/providers", nil, &providers); err != nil {
+		return nil, err
+	}
+	return &providers, nil
+}
+
+// SearchMovies searches by title, narrowed to a release year when year is not empty
+func (c *Client) SearchMovies(ctx context.Context, title, year string) (*SearchResults, error) {
+	query := url.Values{"query": {title}}
+	if year != "" {
+		query.Set("year", year)
+	}
+
+	var results SearchResults
+	if err := c.get(ctx, "/search/movie", query, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
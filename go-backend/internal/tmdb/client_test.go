@@ -0,0 +1,226 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client talking to a server running h
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return New("test-token", WithBaseURL(srv.URL))
+}
+
+func TestAuthorization(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q, want the bearer token", got)
+		}
+		if got := r.Header.Get("Accept"); got != "application/json" {
+			t.Errorf("Accept = %q, want application/json", got)
+		}
+		w.Write([]byte(`{"id": 1}`))
+	})
+	if _, err := c.MovieDetails(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecoding(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/movie/949":
+			w.Write([]byte(`{"id": 949, "title": "Heat", "release_date": "1995-12-15", "runtime": 170,
+				"poster_path": "/heat.jpg", "genres": [{"id": 80, "name": "Crime"}]}`))
+		case "/search/movie":
+			if q := r.URL.Query(); q.Get("query") != "Heat" || q.Get("year") != "1995" {
+				t.Errorf("search query = %v", q)
+			}
+			w.Write([]byte(`{"page": 1, "total_results": 1, "results": [{"id": 949, "title": "Heat"}]}`))
+		case "/movie/949/images":
+			w.Write([]byte(`{"id": 949, "posters": [{"file_path": "/p.jpg", "width": 1000, "height": 1500, "iso_639_1": "en"}]}`))
+		case "/movie/949/credits":
+			w.Write([]byte(`{"id": 949, "cast": [{"name": "Al Pacino", "character": "Vincent Hanna"}],
+				"crew": [{"name": "Michael Mann", "job": "Director"}]}`))
+		case "/movie/949/watch/providers":
+			w.Write([]byte(`{"id": 949, "results": {"GB": {"link": "https://www.themoviedb.org/movie/949/watch?locale=GB",
+				"flatrate": [{"provider_id": 8, "provider_name": "Netflix", "logo_path": "/n.jpg", "display_priority": 1}],
+				"rent": [{"provider_id": 2, "provider_name": "Apple TV"}]}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+
+	movie, err := c.MovieDetails(ctx, "949")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "Heat" || movie.Year() != "1995" || movie.Runtime != 170 || movie.Genres[0].Name != "Crime" {
+		t.Errorf("MovieDetails = %+v", movie)
+	}
+	if got := c.ImageURL(movie.PosterPath, "w500"); got != DefaultImageBaseURL+"/w500/heat.jpg" {
+		t.Errorf("ImageURL = %q", got)
+	}
+
+	images, err := c.MovieImages(ctx, "949")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images.Posters) != 1 || images.Posters[0].FilePath != "/p.jpg" || images.Posters[0].Width != 1000 {
+		t.Errorf("MovieImages = %+v", images)
+	}
+
+	credits, err := c.MovieCredits(ctx, "949")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credits.Cast[0].Character != "Vincent Hanna" || credits.Crew[0].Job != "Director" {
+		t.Errorf("MovieCredits = %+v", credits)
+	}
+
+	providers, err := c.WatchProviders(ctx, "949")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gb := providers.Results["GB"]
+	if providers.ID != 949 || gb.Flatrate[0].ProviderName != "Netflix" || gb.Flatrate[0].DisplayPriority != 1 || gb.Rent[0].ProviderID != 2 || gb.Link == "" {
+		t.Errorf("WatchProviders = %+v", providers)
+	}
+
+	results, err := c.SearchMovies(ctx, "Heat", "1995")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.TotalResults != 1 || results.Results[0].ID != 949 {
+		t.Errorf("SearchMovies = %+v", results)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/movie/404":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status_code": 34, "status_message": "The resource you requested could not be found."}`))
+		case "/movie/401":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status_message": "Invalid API key"}`))
+		default:
+			w.Write([]byte(`{"id": "not a number"}`))
+		}
+	})
+	ctx := context.Background()
+
+	_, err := c.MovieDetails(ctx, "404")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("404 gave %v, want ErrNotFound", err)
+	}
+
+	_, err = c.MovieDetails(ctx, "401")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.StatusMessage != "Invalid API key" {
+		t.Errorf("401 gave %v, want an APIError with the status message", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("401 matched ErrNotFound")
+	}
+
+	if _, err := c.MovieDetails(ctx, "1"); err == nil {
+		t.Errorf("malformed body gave no error")
+	}
+}
+
+func TestRetriesRateLimits(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"id": 1}`))
+	})
+
+	if _, err := c.MovieDetails(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("made %d calls, want 3", n)
+	}
+}
+
+func TestGivesUpOnRateLimits(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := c.MovieDetails(context.Background(), "1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got %v, want a 429 APIError", err)
+	}
+	if n := calls.Load(); n != maxRetries+1 {
+		t.Errorf("made %d calls, want %d", n, maxRetries+1)
+	}
+}
+
+func TestRetryWaitStopsOnCancel(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.MovieDetails(ctx, "1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the context's error", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("waited %s despite the context ending", waited)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header  string
+		attempt int
+		want    time.Duration
+	}{
+		{"3", 0, 3 * time.Second},
+		{"0", 2, 0},
+		{"", 0, time.Second},
+		{"", 2, 4 * time.Second},
+		{"soon", 1, 2 * time.Second},
+		{"-1", 0, time.Second},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, tt.attempt); got != tt.want {
+			t.Errorf("retryAfter(%q, %d) = %s, want %s", tt.header, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestWithTimeoutCopiesClient(t *testing.T) {
+	shared := &http.Client{Timeout: 30 * time.Second}
+	c := New("token", WithHTTPClient(shared), WithTimeout(2*time.Second))
+
+	if shared.Timeout != 30*time.Second {
+		t.Errorf("shared client's timeout changed to %s", shared.Timeout)
+	}
+	if c.httpClient == shared || c.httpClient.Timeout != 2*time.Second {
+		t.Errorf("client timeout = %s, want its own copy with 2s", c.httpClient.Timeout)
+	}
+}
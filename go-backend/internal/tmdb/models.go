@@ -0,0 +1,136 @@
+package tmdb
+
+// Genre is a TMDB genre, the IDs match the ones the frontend sends
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// MovieSummary is a movie as it appears in search results
+type MovieSummary struct {
+	ID            int     `json:"id"`
+	Title         string  `json:"title"`
+	OriginalTitle string  `json:"original_title"`
+	Overview      string  `json:"overview"`
+	ReleaseDate   string  `json:"release_date"`
+	PosterPath    string  `json:"poster_path"`
+	BackdropPath  string  `json:"backdrop_path"`
+	GenreIDs      []int   `json:"genre_ids"`
+	Popularity    float64 `json:"popularity"`
+	VoteAverage   float64 `json:"vote_average"`
+	VoteCount     int     `json:"vote_count"`
+}
+
+// Movie is the full /movie/{id} response
+type Movie struct {
+	ID            int     `json:"id"`
+	IMDbID        string  `json:"imdb_id"`
+	Title         string  `json:"title"`
+	OriginalTitle string  `json:"original_title"`
+	Tagline       string  `json:"tagline"`
+	Overview      string  `json:"overview"`
+	ReleaseDate   string  `json:"release_date"`
+	Runtime       int     `json:"runtime"`
+	Status        string  `json:"status"`
+	PosterPath    string  `json:"poster_path"`
+	BackdropPath  string  `json:"backdrop_path"`
+	Genres        []Genre `json:"genres"`
+	Popularity    float64 `json:"popularity"`
+	VoteAverage   float64 `json:"vote_average"`
+	VoteCount     int     `json:"vote_count"`
+}
+
+// Year returns the release year, or "" when the release date is unknown
+func (m *Movie) Year() string {
+	if len(m.ReleaseDate) < 4 {
+		return ""
+	}
+	return m.ReleaseDate[:4]
+}
+
+// Image is one poster, backdrop or logo file
+type Image struct {
+	FilePath    string  `json:"file_path"`
+	AspectRatio float64 `json:"aspect_ratio"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	Language    string  `json:"iso_639_1"`
+	VoteAverage float64 `json:"vote_average"`
+	VoteCount   int     `json:"vote_count"`
+}
+
+// Images is the /movie/{id}/images response
+type Images struct {
+	ID        int     `json:"id"`
+	Posters   []Image `json:"posters"`
+	Backdrops []Image `json:"backdrops"`
+	Logos     []Image `json:"logos"`
+}
+
+// CastMember is an actor in a movie's credits
+type CastMember struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Character   string `json:"character"`
+	Order       int    `json:"order"`
+	ProfilePath string `json:"profile_path"`
+}
+
+// CrewMember is a crew credit, e.g. Job "Director" in Department "Directing"
+type CrewMember struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Job         string `json:"job"`
+	Department  string `json:"department"`
+	ProfilePath string `json:"profile_path"`
+}
+
+// Credits is the /movie/{id}/credits response
+type Credits struct {
+	ID   int          `json:"id"`
+	Cast []CastMember `json:"cast"`
+	Crew []CrewMember `json:"crew"`
+}
+
+// Directors returns the names of everyone credited as director
+func (c *Credits) Directors() []string {
+	var names []string
+	for _, member := range c.Crew {
+		if member.Job == "Director" {
+			names = append(names, member.Name)
+		}
+	}
+	return names
+}
+
+// SearchResults is a page of /search/movie results
+type SearchResults struct {
+	Page         int            `json:"page"`
+	TotalPages   int            `json:"total_pages"`
+	TotalResults int            `json:"total_results"`
+	Results      []MovieSummary `json:"results"`
+}
+
+// Provider is a streaming, rental or purchase service
+type Provider struct {
+	ProviderID      int    `json:"provider_id"`
+	ProviderName    string `json:"provider_name"`
+	LogoPath        string `json:"logo_path"`
+	DisplayPriority int    `json:"display_priority"`
+}
+
+// CountryProviders lists the providers for one country
+type CountryProviders struct {
+	Link     string     `json:"link"`
+	Flatrate []Provider `json:"flatrate"`
+	Rent     []Provider `json:"rent"`
+	Buy      []Provider `json:"buy"`
+	Free     []Provider `json:"free"`
+	Ads      []Provider `json:"ads"`
+}
+
+// WatchProviders is the /movie/{id}/watch/providers response, keyed by ISO 3166-1 country code
+type WatchProviders struct {
+	ID      int                         `json:"id"`
+	Results map[string]CountryProviders `json:"results"`
+}
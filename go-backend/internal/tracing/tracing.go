@@ -0,0 +1,144 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are exported over OTLP/HTTP, to a
+// collector or straight to Jaeger, or printed to stdout. Until Setup installs a provider every
+// tracer is a no-op, so instrumented code costs next to nothing when tracing is off.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters are the span exporters Setup accepts, "none" turning tracing off
+var Exporters = []string{"none", "otlp", "stdout"}
+
+// Options configures Setup
+type Options struct {
+	// Exporter is one of Exporters
+	Exporter string
+	// Endpoint is the OTLP/HTTP endpoint, e.g. http://localhost:4318. When empty the exporter
+	// falls back to OTEL_EXPORTER_OTLP_ENDPOINT and then localhost.
+	Endpoint string
+	// SampleRatio is the fraction of new traces recorded, 0 to 1. Traces started upstream
+	// follow the caller's sampling decision.
+	SampleRatio    float64
+	ServiceName    string
+	ServiceVersion string
+}
+
+// Setup installs the global tracer provider and W3C trace context propagation. The returned
+// function flushes buffered spans and must be called before exit.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(opts.Exporter) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var o []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			o = append(o, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, o...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected none, otlp or stdout", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(opts.ServiceVersion),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the named tracer from the global provider
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Handler starts a server span for every request to h, continuing any trace the caller sent.
+// Spans are named by method until the mux matches a route, see NameSpan.
+func Handler(h http.Handler) http.Handler {
+	return otelhttp.NewHandler(h, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return r.Method }),
+	)
+}
+
+// NameSpan names the request's server span after the matched route pattern, e.g.
+// "GET /api/v1/recommend", and tags it with the request ID
+func NameSpan(r *http.Request, requestID string) {
+	span := trace.SpanFromContext(r.Context())
+	if r.Pattern != "" {
+		span.SetName(r.Pattern)
+		if _, route, ok := strings.Cut(r.Pattern, " "); ok {
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+	}
+	if requestID != "" {
+		span.SetAttributes(attribute.String("request.id", requestID))
+	}
+}
+
+// Transport traces every request sent through base, http.DefaultTransport when nil, as a
+// client span below the span in the request's context
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// HTTPClient returns a client with timeout whose requests are traced
+func HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Transport(nil)}
+}
+
+// End records err, if any, on span and ends it. It is meant to be deferred with a pointer to
+// the caller's named error result.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the trace ctx belongs to, or "" outside a sampled trace
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsSampled() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
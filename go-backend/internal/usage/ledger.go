@@ -0,0 +1,243 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go-backend/internal/store"
+)
+
+// ErrBudgetExhausted is returned once the day's token budget has been spent
+var ErrBudgetExhausted = errors.New("daily AI token budget exhausted")
+
+// retainDays is how many days of usage are kept in memory for the admin report
+const retainDays = 30
+
+// maxClientsPerDay caps how many clients are counted separately in a day. Past it, calls are
+// charged to OtherClients, so a flood of new IPs can't grow the ledger without bound.
+const maxClientsPerDay = 10000
+
+// OtherClients is the client calls are charged to once a day already has maxClientsPerDay
+const OtherClients = "other"
+
+// Tokens counts model calls and the tokens they consumed
+type Tokens struct {
+	Requests int64 `json:"requests"`
+	Prompt   int64 `json:"prompt_tokens"`
+	Response int64 `json:"response_tokens"`
+	Total    int64 `json:"total_tokens"`
+}
+
+func (t *Tokens) add(o Tokens) {
+	t.Requests += o.Requests
+	t.Prompt += o.Prompt
+	t.Response += o.Response
+	t.Total += o.Total
+}
+
+type day struct {
+	total Tokens
+	// reserved is held for calls still in flight
+	reserved int64
+	clients  map[string]*Tokens
+}
+
+// Store is where a ledger keeps its usage, see store.Store
+type Store interface {
+	AddUsage(ctx context.Context, u *store.Usage) error
+	ListUsage(ctx context.Context, since string) ([]store.Usage, error)
+}
+
+// Ledger aggregates token usage per UTC day and per client, and enforces a daily budget
+type Ledger struct {
+	budget int64
+	now    func() time.Time
+	db     Store
+
+	mu   sync.Mutex
+	days map[string]*day
+}
+
+// NewLedger creates a ledger. A dailyBudget of 0 or less means unlimited.
+func NewLedger(dailyBudget int64) *Ledger {
+	return &Ledger{
+		budget: dailyBudget,
+		now:    time.Now,
+		days:   make(map[string]*day),
+	}
+}
+
+func (l *Ledger) today() string {
+	return l.now().UTC().Format("2006-01-02")
+}
+
+// Persist loads the retained days from db and writes every call through to it, so usage and
+// the budget survive a restart. It must be called before the ledger is shared.
+func (l *Ledger) Persist(ctx context.Context, db Store) error {
+	since := l.now().UTC().AddDate(0, 0, -retainDays).Format("2006-01-02")
+	rows, err := db.ListUsage(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, u := range rows {
+		l.add(u.Date, u.Client, Tokens{
+			Requests: u.Requests,
+			Prompt:   u.PromptTokens,
+			Response: u.ResponseTokens,
+			Total:    u.PromptTokens + u.ResponseTokens,
+		})
+	}
+	l.db = db
+	return nil
+}
+
+// Reserve holds estimate tokens of today's budget for a model call, or returns
+// ErrBudgetExhausted if they don't fit beside what has been spent and what other calls hold.
+// Holding the estimate up front stops concurrent calls from all passing the check and then
+// overshooting together. The returned settle replaces the estimate with the tokens the call
+// used and charges them to the client in ctx; a call that failed settles with 0 and 0, which
+// only releases the hold. settle must be called exactly once.
+func (l *Ledger) Reserve(ctx context.Context, estimate int64) (settle func(promptTokens, responseTokens int32), err error) {
+	key := l.today()
+
+	l.mu.Lock()
+	d := l.day(key)
+	if l.budget > 0 && d.total.Total+d.reserved+estimate > l.budget {
+		l.mu.Unlock()
+		return nil, ErrBudgetExhausted
+	}
+	d.reserved += estimate
+	l.mu.Unlock()
+
+	return func(promptTokens, responseTokens int32) {
+		t := Tokens{
+			Requests: 1,
+			Prompt:   int64(promptTokens),
+			Response: int64(responseTokens),
+			Total:    int64(promptTokens) + int64(responseTokens),
+		}
+
+		l.mu.Lock()
+		// Settled against the day the hold was taken, even if the call ran past midnight
+		l.day(key).reserved -= estimate
+		if t.Total == 0 {
+			l.mu.Unlock()
+			return
+		}
+		client := l.add(key, ClientFrom(ctx), t)
+		db := l.db
+		l.mu.Unlock()
+
+		if db == nil {
+			return
+		}
+		// The call has been paid for even if the request that made it has gone away
+		u := &store.Usage{Date: key, Client: client, Requests: t.Requests, PromptTokens: t.Prompt, ResponseTokens: t.Response}
+		if err := db.AddUsage(context.WithoutCancel(ctx), u); err != nil {
+			slog.WarnContext(ctx, "Failed to persist AI usage", "error", err)
+		}
+	}, nil
+}
+
+// day returns the day under key, creating it. Callers must hold l.mu.
+func (l *Ledger) day(key string) *day {
+	d, ok := l.days[key]
+	if !ok {
+		d = &day{clients: make(map[string]*Tokens)}
+		l.days[key] = d
+		l.prune()
+	}
+	return d
+}
+
+// add charges t to client on the day under key and returns the client it was charged to,
+// OtherClients once the day is full. Callers must hold l.mu.
+func (l *Ledger) add(key, client string, t Tokens) string {
+	d := l.day(key)
+	d.total.add(t)
+
+	c, ok := d.clients[client]
+	if !ok {
+		if len(d.clients) >= maxClientsPerDay {
+			client = OtherClients
+			c = d.clients[client]
+		}
+		if c == nil {
+			c = &Tokens{}
+			d.clients[client] = c
+		}
+	}
+	c.add(t)
+	return client
+}
+
+// prune drops days older than retainDays. Callers must hold l.mu.
+func (l *Ledger) prune() {
+	cutoff := l.now().UTC().AddDate(0, 0, -retainDays).Format("2006-01-02")
+	for key := range l.days {
+		if key < cutoff {
+			delete(l.days, key)
+		}
+	}
+}
+
+// DayReport is one day of usage, overall and per client
+type DayReport struct {
+	Date    string            `json:"date"`
+	Total   Tokens            `json:"total"`
+	Clients map[string]Tokens `json:"clients"`
+}
+
+// Report is a snapshot of the ledger for the admin endpoint
+type Report struct {
+	DailyBudget    int64       `json:"daily_budget"`
+	RemainingToday int64       `json:"remaining_today"`
+	Days           []DayReport `json:"days"`
+}
+
+// Report returns every retained day, newest first
+func (l *Ledger) Report() Report {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := Report{DailyBudget: l.budget, RemainingToday: -1}
+	for key, d := range l.days {
+		dr := DayReport{Date: key, Total: d.total, Clients: make(map[string]Tokens, len(d.clients))}
+		for client, t := range d.clients {
+			dr.Clients[client] = *t
+		}
+		r.Days = append(r.Days, dr)
+	}
+	sort.Slice(r.Days, func(i, j int) bool { return r.Days[i].Date > r.Days[j].Date })
+
+	if l.budget > 0 {
+		r.RemainingToday = l.budget
+		if d, ok := l.days[l.today()]; ok {
+			// Tokens held by calls in flight can't be promised to anyone else either
+			r.RemainingToday = max(l.budget-d.total.Total-d.reserved, 0)
+		}
+	}
+	return r
+}
+
+type clientKey struct{}
+
+// WithClient tags ctx with the identity (API key or IP) that usage should be charged to
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFrom returns the identity set by WithClient, or "unknown"
+func ClientFrom(ctx context.Context) string {
+	if client, ok := ctx.Value(clientKey{}).(string); ok && client != "" {
+		return client
+	}
+	return "unknown"
+}
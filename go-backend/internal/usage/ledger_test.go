@@ -0,0 +1,99 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"go-backend/internal/store"
+)
+
+func TestReserveHoldsBudget(t *testing.T) {
+	l := NewLedger(1000)
+	ctx := context.Background()
+
+	settle, err := l.Reserve(ctx, 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first call is still in flight, its estimate is held
+	if _, err := l.Reserve(ctx, 600); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("second Reserve = %v, want ErrBudgetExhausted", err)
+	}
+	if r := l.Report(); r.RemainingToday != 400 {
+		t.Fatalf("RemainingToday = %d with 600 held, want 400", r.RemainingToday)
+	}
+
+	// It used less than estimated, which frees the rest
+	settle(100, 100)
+	settle, err = l.Reserve(ctx, 600)
+	if err != nil {
+		t.Fatalf("Reserve after settling = %v", err)
+	}
+	// A failed call only releases its hold
+	settle(0, 0)
+
+	r := l.Report()
+	if r.RemainingToday != 800 || r.Days[0].Total.Requests != 1 {
+		t.Fatalf("Report = %+v, want 800 remaining after 1 request", r)
+	}
+}
+
+func TestClientsAreCapped(t *testing.T) {
+	l := NewLedger(0)
+	for i := range maxClientsPerDay + 5 {
+		settle, err := l.Reserve(WithClient(context.Background(), fmt.Sprintf("ip:%d", i)), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		settle(1, 1)
+	}
+
+	day := l.Report().Days[0]
+	if len(day.Clients) != maxClientsPerDay+1 {
+		t.Fatalf("got %d clients, want %d", len(day.Clients), maxClientsPerDay+1)
+	}
+	if other := day.Clients[OtherClients]; other.Requests != 5 {
+		t.Fatalf("%s has %d requests, want 5", OtherClients, other.Requests)
+	}
+	if day.Total.Requests != maxClientsPerDay+5 {
+		t.Fatalf("total has %d requests, want %d", day.Total.Requests, maxClientsPerDay+5)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	ctx := context.Background()
+	db, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	l := NewLedger(1000)
+	if err := l.Persist(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		settle, err := l.Reserve(WithClient(ctx, "key:abcd1234"), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		settle(30, 20)
+	}
+
+	// A restarted server picks up where the last one left off
+	restarted := NewLedger(1000)
+	if err := restarted.Persist(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	r := restarted.Report()
+	if r.RemainingToday != 900 {
+		t.Fatalf("RemainingToday = %d, want 900", r.RemainingToday)
+	}
+	want := Tokens{Requests: 2, Prompt: 60, Response: 40, Total: 100}
+	if got := r.Days[0].Clients["key:abcd1234"]; got != want {
+		t.Fatalf("client usage = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+// Package version reports which build of the server is running. Release builds set Version and
+// Commit with -ldflags "-X go-backend/internal/version.Version=v1.2.3 -X ...Commit=abc123";
+// otherwise they come from the module and VCS information Go embeds in the binary.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set at build time with -ldflags -X
+var (
+	Version = ""
+	Commit  = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	// Modified is true when the binary was built from a tree with uncommitted changes
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the running build's version information
+var Get = sync.OnceValue(func() Info {
+	info := Info{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				info.BuildTime = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+})
+
+// String is the version and short commit, e.g. "v1.2.3 (abc1234)"
+func (i Info) String() string {
+	if i.Commit == "" {
+		return i.Version
+	}
+	commit := i.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if i.Modified {
+		commit += "-dirty"
+	}
+	return i.Version + " (" + commit + ")"
+}
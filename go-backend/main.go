@@ -1,21 +1,40 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"image"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"go-backend/internal/ai"
+	"go-backend/internal/apierror"
+	"go-backend/internal/config"
+	"go-backend/internal/health"
+	"go-backend/internal/imagecache"
+	"go-backend/internal/logging"
+	"go-backend/internal/metrics"
+	"go-backend/internal/ratelimit"
 	"go-backend/internal/scraper"
-
-	"github.com/gocolly/colly/v2"
-	"golang.org/x/time/rate"
+	"go-backend/internal/share"
+	"go-backend/internal/store"
+	"go-backend/internal/tracing"
+	"go-backend/internal/usage"
+	"go-backend/internal/version"
 )
 
 type HealthResponse struct {
@@ -23,114 +42,119 @@ type HealthResponse struct {
 	Timestamp   string `json:"timestamp"`
 	Service     string `json:"service"`
 	Version     string `json:"version"`
+	Commit      string `json:"commit,omitempty"`
 	Environment string `json:"environment"`
 }
 
-// Rate limiter map
-var (
-	limiters = make(map[string]*rate.Limiter)
-	mu       sync.RWMutex
+// Rate limit policies, named by routes
+const (
+	policyDefault   = "default"
+	policyRecommend = "recommend"
+	policyWatchlist = "watchlist"
 )
 
-// Get rate limiter for IP
-func getLimiter(ip string) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock()
-
-	limiter, exists := limiters[ip]
-	if !exists {
-		// Default: 100 requests per 15 minutes
-		requests := 100
-		window := 15 * time.Minute
-
-		// Override from environment if set
-		if envRequests := os.Getenv("RATE_LIMIT_REQUESTS"); envRequests != "" {
-			if r, err := fmt.Sscanf(envRequests, "%d", &requests); err == nil && r == 1 {
-				// requests is already set
-			}
-		}
-
-		limiter = rate.NewLimiter(rate.Every(window/time.Duration(requests)), requests)
-		limiters[ip] = limiter
+// newRateLimit returns middleware limiting each client, told apart by clientIP, by a named
+// policy, with a bucket per client and policy so the budgets are separate. Responses carry
+// RateLimit-* headers. newLimiter picks where the buckets live; if they can't be reached
+// requests are let through.
+func newRateLimit(cfg config.RateLimit, newLimiter func(ratelimit.Policy) ratelimit.Limiter, clientIP func(*http.Request) string) func(policy string, h http.HandlerFunc) http.HandlerFunc {
+	limiters := make(map[string]ratelimit.Limiter)
+	for _, p := range []ratelimit.Policy{
+		{Name: policyDefault, Requests: cfg.Requests, Window: cfg.Window},
+		{Name: policyRecommend, Requests: cfg.Recommend.Requests, Window: cfg.Recommend.Window},
+		{Name: policyWatchlist, Requests: cfg.Watchlist.Requests, Window: cfg.Watchlist.Window},
+	} {
+		limiters[p.Name] = newLimiter(p)
 	}
 
-	return limiter
-}
-
-// Rate limiting middleware
-func withRateLimit(h http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return func(policy string, h http.HandlerFunc) http.HandlerFunc {
 		// Skip rate limiting if disabled
-		if os.Getenv("ENABLE_RATE_LIMITING") == "false" {
-			h(w, r)
-			return
+		if !cfg.Enabled || policy == "" {
+			return h
 		}
-
-		ip := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			ip = strings.Split(forwarded, ",")[0]
+		limiter, ok := limiters[policy]
+		if !ok {
+			panic("unknown rate limit policy " + policy)
 		}
 
-		limiter := getLimiter(ip)
-		if !limiter.Allow() {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error":   "Rate limit exceeded",
-				"message": "Too many requests, please try again later",
-			})
-			return
+		return func(w http.ResponseWriter, r *http.Request) {
+			d, err := limiter.Allow(r.Context(), clientIP(r))
+			if err != nil {
+				slog.WarnContext(r.Context(), "Rate limiter unavailable, allowing request", "policy", policy, "error", err)
+				h(w, r)
+				return
+			}
+			ratelimit.SetHeaders(w.Header(), limiter.Policy(), d)
+			if !d.Allowed {
+				metrics.RejectRateLimited(r.Pattern, policy)
+				apierror.Write(w, r, apierror.ErrRateLimited.WithDetails(map[string]string{"policy": policy}))
+				return
+			}
+
+			h(w, r)
 		}
+	}
+}
 
-		h(w, r)
+// clientIP returns the caller's address, see clientip.Resolver
+func (s *server) clientIP(r *http.Request) string {
+	return s.clientIPs.IP(r)
+}
+
+// clientID identifies who AI usage is charged to: the issued key if X-API-Key holds one,
+// otherwise the IP. Unknown and revoked keys are charged to the IP, so a made-up key can't
+// move spend onto someone else or dodge it.
+func (s *server) clientID(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		k, err := s.db.LookupAPIKey(r.Context(), key)
+		if err == nil {
+			// Only the prefix, so full keys never end up in reports or logs
+			return "key:" + k.Prefix
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			slog.WarnContext(r.Context(), "Failed to look up API key", "error", err)
+		}
 	}
+	return "ip:" + s.clientIP(r)
 }
 
-// CORS middleware to handle cross-origin requests
-func withCORS(h http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Get allowed origins from environment
-		allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
-		if allowedOrigins == "" {
-			// Default for development
-			allowedOrigins = "http://localhost:5173,http://localhost:3000"
-		}
-
-		origin := r.Header.Get("Origin")
-		origins := strings.Split(allowedOrigins, ",")
-
-		// Check if origin is allowed
-		allowed := false
-		for _, allowedOrigin := range origins {
-			if strings.TrimSpace(allowedOrigin) == origin {
-				allowed = true
-				break
+// newCORS returns middleware handling cross-origin requests from the allowed origins
+func newCORS(allowedOrigins []string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// Check if origin is allowed
+			origin := r.Header.Get("Origin")
+			if slices.Contains(allowedOrigins, origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
 			}
-		}
 
-		if allowed {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-		}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			// Let the client read its remaining budget and how long to back off
+			w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After, X-Request-ID")
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
+			// Handle preflight OPTIONS requests
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
 
-		// Handle preflight OPTIONS requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+			// Call the original handler
+			h(w, r)
 		}
-
-		// Call the original handler
-		h(w, r)
 	}
 }
 
-// Logging middleware
+// Logging middleware, every line logged below it carries the matched route. It also records
+// the request's metrics.
 func withLogging(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		if r.Pattern != "" {
+			r = r.WithContext(logging.With(r.Context(), slog.String("route", r.Pattern)))
+		}
+		tracing.NameSpan(r, logging.RequestID(r.Context()))
 
 		// Create response writer wrapper to capture status code
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
@@ -140,12 +164,17 @@ func withLogging(h http.HandlerFunc) http.HandlerFunc {
 
 		// Log the request
 		duration := time.Since(start)
-		log.Printf("INFO: %s %s %d %v %s",
-			r.Method,
-			r.URL.Path,
-			wrapped.statusCode,
-			duration,
-			r.RemoteAddr,
+		metrics.ObserveRequest(r.Pattern, r.Method, wrapped.statusCode, duration)
+		level := slog.LevelInfo
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(r.Context(), level, "Request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", wrapped.statusCode),
+			slog.Duration("duration", duration),
+			slog.String("remote", r.RemoteAddr),
 		)
 	}
 }
@@ -161,49 +190,115 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	response := HealthResponse{
-		Status:      "OK",
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
-		Service:     "Go API Server",
-		Version:     "1.0.0",
-		Environment: os.Getenv("NODE_ENV"),
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Flush lets streaming handlers flush through the logging wrapper
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// healthHandler is the liveness check: it answers as long as the process can serve requests,
+// whatever state its dependencies are in
+func healthHandler(env string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		build := version.Get()
+		response := HealthResponse{
+			Status:      "OK",
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+			Service:     "Go API Server",
+			Version:     build.Version,
+			Commit:      build.Commit,
+			Environment: env,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
 }
 
-func watchlistHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.URL.Query().Get("username")
+// newReadiness checks the store, which the server can't work without, and Gemini, Letterboxd
+// and Redis, which only degrade it; without Redis requests aren't limited or cached
+func (s *server) newReadiness() *health.Checker {
+	checks := []health.Check{
+		{Name: "store", Critical: true, Run: s.db.Ping},
+		{Name: "gemini", Run: func(ctx context.Context) error {
+			if s.recommender == nil {
+				return ai.ErrNotConfigured
+			}
+			return s.recommender.Ping(ctx)
+		}},
+		{Name: "letterboxd", Run: scraper.Probe},
+	}
+	if s.state.redis != nil {
+		checks = append(checks, health.Check{Name: "redis", Run: s.state.ping})
+	}
+	return health.NewChecker(3*time.Second, 10*time.Second, checks...)
+}
+
+// readyHandler is the readiness check, 503 while a critical dependency is failing
+func readyHandler(checker *health.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Report(r.Context())
+		status := http.StatusOK
+		if report.Status == health.StatusFail {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(struct {
+			health.Report
+			Version version.Info `json:"version"`
+		}{report, version.Get()})
+	}
+}
+
+// withUsername tags the request's log lines with the Letterboxd user it is for
+func withUsername(r *http.Request, username string) *http.Request {
+	return r.WithContext(logging.With(r.Context(), slog.String("username", username)))
+}
+
+// errNoFilms is returned when a watchlist is empty or private
+var errNoFilms = apierror.New(http.StatusNotFound, "no_films", "No films found in watchlist")
+
+func (s *server) watchlistHandler(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
 	if username == "" {
-		http.Error(w, `{"error": "Username parameter is required"}`, http.StatusBadRequest)
+		apierror.Write(w, r, apierror.ErrUsernameRequired)
 		return
 	}
+	r = withUsername(r, username)
 
 	// Get genres parameter (optional)
 	genres := r.URL.Query().Get("genres")
 
-	// Debug logging
-	log.Printf("DEBUG: Watchlist request - username: %s, genres: %s", username, genres)
+	slog.DebugContext(r.Context(), "Watchlist request", "genres", genres)
 
-	// Get watchlist using the ScrapeWatchlist function with genres filter
-	films, err := scraper.ScrapeWatchlist(username, genres)
+	// Get watchlist using the ScrapeWatchlist function with genres filter, or from the cache
+	films, err := s.state.cachedWatchlist(r.Context(), username+":"+genres, func(ctx context.Context) ([]scraper.Film, error) {
+		return scraper.ScrapeWatchlistContext(ctx, username, genres)
+	})
 	if err != nil {
-		log.Printf("DEBUG: ScrapeWatchlist error: %v", err)
-		http.Error(w, `{"error": "Failed to get watchlist"}`, http.StatusInternalServerError)
+		slog.WarnContext(r.Context(), "Failed to scrape watchlist", "error", err)
+		apierror.Write(w, r, apierror.ErrWatchlistFailed)
 		return
 	}
 
-	log.Printf("DEBUG: Found %d films in watchlist", len(films))
+	slog.DebugContext(r.Context(), "Found films in watchlist", "films", len(films))
 
 	if len(films) == 0 {
 		if genres != "" {
-			log.Printf("DEBUG: No films found for genres: %s", genres)
-			http.Error(w, `{"error": "No films found in watchlist for the selected genres"}`, http.StatusNotFound)
+			slog.DebugContext(r.Context(), "No films found for genres", "genres", genres)
+			apierror.Write(w, r, apierror.New(http.StatusNotFound, "no_films_for_genres", "No films found in watchlist for the selected genres").WithDetails(map[string]string{"genres": genres}))
 		} else {
-			log.Printf("DEBUG: No films found in watchlist")
-			http.Error(w, `{"error": "No films found in watchlist"}`, http.StatusNotFound)
+			slog.DebugContext(r.Context(), "No films found in watchlist")
+			apierror.Write(w, r, errNoFilms)
 		}
 		return
 	}
@@ -213,138 +308,799 @@ func watchlistHandler(w http.ResponseWriter, r *http.Request) {
 	randomIndex := rand.Intn(len(films))
 	selectedFilm := films[randomIndex]
 
-	log.Printf("DEBUG: Selected film: %s (%s)", selectedFilm.Name, selectedFilm.Year)
+	slog.DebugContext(r.Context(), "Selected film", "film", selectedFilm.Name, "year", selectedFilm.Year)
+
+	// The scrape only fetches detail pages for the first few films, fill in a missing poster
+	if selectedFilm.Image == "" {
+		selectedFilm.Image = s.resolvePoster(r.Context(), scraper.PosterQuery{
+			FilmURL: selectedFilm.Slug,
+			Title:   selectedFilm.Name,
+			Year:    selectedFilm.Year,
+		})
+	}
+	if preview := s.posterPreview(r.Context(), selectedFilm.Slug, selectedFilm.Image); preview != nil {
+		selectedFilm.Blurhash = preview.Blurhash
+		selectedFilm.Palette = preview.Palette
+	}
+	if err := s.db.TouchUser(r.Context(), username); err != nil {
+		slog.WarnContext(r.Context(), "Failed to record user", "error", err)
+	}
+	selectedFilm.ShareID = s.recordPick(r.Context(), &store.Pick{
+		Username:  username,
+		Source:    store.SourceWatchlist,
+		FilmURL:   selectedFilm.Slug,
+		Name:      selectedFilm.Name,
+		Year:      selectedFilm.Year,
+		Image:     selectedFilm.Image,
+		PickedFor: username,
+	})
+	selectedFilm.Image = s.posterOrPlaceholder(r, selectedFilm.Slug, selectedFilm.Image)
 
 	// Return the single film as JSON
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(selectedFilm)
 }
 
-func recommendHandler(w http.ResponseWriter, r *http.Request) {
+// watchlistExportHandler streams a user's whole watchlist as CSV, JSON Lines or Letterboxd's
+// import CSV, rather than picking one film
+func (s *server) watchlistExportHandler(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
+	if username == "" {
+		apierror.Write(w, r, apierror.ErrUsernameRequired)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if !slices.Contains(scraper.ExportFormats, format) {
+		apierror.Write(w, r, apierror.Newf(http.StatusBadRequest, "invalid_format", "Unknown format, expected one of: %s", strings.Join(scraper.ExportFormats, ", ")).
+			WithDetails(map[string][]string{"formats": scraper.ExportFormats}))
+		return
+	}
+
+	r = withUsername(r, username)
+	slog.DebugContext(r.Context(), "Watchlist export request", "format", format)
+
+	// Scraping a whole watchlist and streaming it can outlast the server's write timeout,
+	// the request context still stops it on shutdown or when the client goes away
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		slog.DebugContext(r.Context(), "Could not lift write deadline for export", "error", err)
+	}
+
+	films, err := s.state.cachedWatchlist(r.Context(), username+":all", func(ctx context.Context) ([]scraper.Film, error) {
+		return scraper.GetWatchlistContext(ctx, username)
+	})
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to scrape watchlist", "error", err)
+		apierror.Write(w, r, apierror.ErrWatchlistFailed)
+		return
+	}
+	if len(films) == 0 {
+		apierror.Write(w, r, errNoFilms)
+		return
+	}
+
+	fw, err := scraper.NewFilmWriter(w, format)
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, "invalid_format", err.Error()))
+		return
+	}
+	name := username + "-watchlist"
+	if format == "letterboxd" {
+		name += "-letterboxd"
+	}
+	w.Header().Set("Content-Type", fw.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, fw.Extension()))
+
+	// Flush every so often so big watchlists start downloading straight away
+	flusher, _ := w.(http.Flusher)
+	for i, film := range films {
+		if err := fw.Write(film); err != nil {
+			slog.DebugContext(r.Context(), "Watchlist export aborted", "error", err)
+			return
+		}
+		if flusher != nil && i%100 == 99 {
+			fw.Flush()
+			flusher.Flush()
+		}
+	}
+	fw.Flush()
+}
+
+// maxPickedForLength caps the name in the "for" parameter, in characters
+const maxPickedForLength = 40
+
+// pickedFor is who a recommendation is for, from the "for" parameter. It is drawn on public share
+// cards and pages, so it is stripped of control and formatting characters and cut short.
+func pickedFor(r *http.Request) string {
+	name := []rune(ai.Sanitize(r.URL.Query().Get("for")))
+	if len(name) > maxPickedForLength {
+		name = name[:maxPickedForLength]
+	}
+	return strings.TrimSpace(string(name))
+}
+
+func (s *server) recommendHandler(w http.ResponseWriter, r *http.Request) {
 	prompt := r.URL.Query().Get("prompt")
+	// Saved with the pick in the form the model saw, without hidden characters
+	userPrompt := ai.Sanitize(prompt)
 	if prompt == "" {
 		prompt = "Give me a recommendation for a single, interesting, and critically acclaimed movie from any genre or era."
 	}
 
-	log.Printf("DEBUG: Recommend request - prompt: %s", prompt)
+	// Optional prompt mode and template variables
+	opts := ai.RecommendOptions{
+		Mode:  r.URL.Query().Get("mode"),
+		Taste: r.URL.Query().Get("taste"),
+		Count: 1,
+	}
+	if exclude := r.URL.Query().Get("exclude"); exclude != "" {
+		for _, title := range strings.Split(exclude, ",") {
+			if title = strings.TrimSpace(title); title != "" {
+				opts.Exclusions = append(opts.Exclusions, title)
+			}
+		}
+	}
+	if count := r.URL.Query().Get("count"); count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 || n > 5 {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, "invalid_count", "count must be a number between 1 and 5"))
+			return
+		}
+		opts.Count = n
+	}
 
-	// Get the recommendation
-	log.Printf("DEBUG: Calling ai.GetRecommendation")
-	movieData, err := ai.GetRecommendation(prompt)
+	overrides, err := parseOverrides(r)
 	if err != nil {
-		log.Printf("ERROR: Failed to get recommendation: %v", err)
-		http.Error(w, fmt.Sprintf(`{"error": "Failed to get recommendation: %v"}`, err), http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, "invalid_override", err.Error()))
 		return
 	}
+	opts.Overrides = overrides
 
-	log.Printf("DEBUG: Got movie data: %+v", movieData)
+	slog.DebugContext(r.Context(), "Recommend request", "prompt", prompt, "mode", opts.Mode)
 
-	// Get poster from Letterboxd og:image
-	log.Printf("DEBUG: Getting poster for %s", movieData.Slug)
-	posterURL := getPosterFromLetterboxdOgImage(movieData.Slug)
-	if posterURL != "" {
-		movieData.Image = posterURL
-		log.Printf("DEBUG: Got poster for %s: %s", movieData.Name, posterURL)
-	} else {
-		log.Printf("DEBUG: No poster found for %s", movieData.Name)
+	// Get the recommendations
+	if s.recommender == nil {
+		s.writeAIError(w, r, ai.ErrNotConfigured)
+		return
+	}
+	r = r.WithContext(usage.WithClient(r.Context(), s.clientID(r)))
+	movies, err := s.recommender.Recommend(r.Context(), prompt, opts)
+	if s.writeAIError(w, r, err) {
+		return
+	}
+
+	for i := range movies {
+		movieData := &movies[i]
+		slog.DebugContext(r.Context(), "Recommended film", "film", movieData.Name, "year", movieData.Year, "slug", movieData.Slug)
+
+		// Get poster from the first source in the chain that has one
+		movieData.Image = s.resolvePoster(r.Context(), scraper.PosterQuery{
+			FilmURL: movieData.Slug,
+			Title:   movieData.Name,
+			Year:    movieData.Year,
+			TMDBID:  movieData.TMDBID,
+		})
+		if preview := s.posterPreview(r.Context(), movieData.Slug, movieData.Image); preview != nil {
+			movieData.Blurhash = preview.Blurhash
+			movieData.Palette = preview.Palette
+		}
+		movieData.ShareID = s.recordPick(r.Context(), &store.Pick{
+			Source:    store.SourceRecommend,
+			FilmURL:   movieData.Slug,
+			Name:      movieData.Name,
+			Year:      movieData.Year,
+			Image:     movieData.Image,
+			PickedFor: pickedFor(r),
+			Prompt:    userPrompt,
+		})
+		movieData.Image = s.posterOrPlaceholder(r, movieData.Slug, movieData.Image)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(movieData)
-	log.Printf("DEBUG: Successfully returned movie data")
-}
-
-// getPosterFromLetterboxdOgImage extracts og:image from Letterboxd film page
-func getPosterFromLetterboxdOgImage(letterboxdURL string) string {
-	var posterURL string
-	var mu sync.Mutex
-
-	// Create a collector for film detail page
-	c := colly.NewCollector(
-		colly.Async(true),
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-	)
-	c.Limit(&colly.LimitRule{
-		DomainGlob:  "*letterboxd.com*",
-		Parallelism: 1,
-		RandomDelay: 500 * time.Millisecond,
+	// A single pick keeps the original object response, multi-film modes return an array
+	if opts.Count == 1 && len(movies) == 1 {
+		json.NewEncoder(w).Encode(movies[0])
+	} else {
+		json.NewEncoder(w).Encode(movies)
+	}
+}
+
+// posterHandler serves /api/v1/posters/{slug}?w=<width> from the on-disk cache, fetching it through the
+// poster chain the first time, and the bundled placeholder when no source has one
+func (s *server) posterHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if !scraper.IsFilmSlug(slug) {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, "invalid_slug", "Invalid film slug"))
+		return
+	}
+
+	width := 0
+	if v := r.URL.Query().Get("w"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, "invalid_width", "w must be a positive width in pixels"))
+			return
+		}
+		width = n
+	}
+
+	img, err := s.images.Get(r.Context(), slug, width, func(ctx context.Context) (string, error) {
+		result, err := s.posters.Resolve(ctx, scraper.PosterQuery{FilmURL: scraper.FilmURL(slug)})
+		if err != nil {
+			return "", err
+		}
+		return result.URL, nil
 	})
+	if err != nil {
+		if !errors.Is(err, imagecache.ErrNotFound) {
+			slog.ErrorContext(r.Context(), "Failed to get poster", "slug", slug, "error", err)
+		}
+		img = s.images.Placeholder(width)
+	}
+
+	// Real posters rarely change, placeholders are rechecked soon in case a source catches up
+	if img.Placeholder {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=604800")
+	}
+	w.Header().Set("ETag", img.ETag)
+
+	if match := r.Header.Get("If-None-Match"); match != "" && match == img.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", img.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(img.Data)))
+	w.Write(img.Data)
+}
+
+var errShareNotFound = apierror.New(http.StatusNotFound, "share_not_found", "Share not found")
+
+// shareHandler serves /share/{id}, a page whose Open Graph tags unfurl into the pick's card
+// in chat apps, and /share/{id}.png, the card itself
+func (s *server) shareHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, isCard := strings.CutSuffix(r.PathValue("id"), ".png")
+		if !store.ValidPickID(id) {
+			apierror.Write(w, r, errShareNotFound)
+			return
+		}
+		pick, err := s.db.GetPick(r.Context(), id)
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.Write(w, r, errShareNotFound)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to load pick", "id", id, "error", err)
+			apierror.Write(w, r, apierror.New(http.StatusInternalServerError, "share_failed", "Failed to load share"))
+			return
+		}
+
+		if !isCard {
+			page, err := share.RenderPage(*pick, s.publicBaseURL(r))
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Failed to render share page", "id", id, "error", err)
+				apierror.Write(w, r, apierror.New(http.StatusInternalServerError, "share_failed", "Failed to render share page"))
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(page)
+			return
+		}
+
+		card, err := share.RenderCard(*pick, s.sharePoster(r.Context(), pick), s.publicBaseURL(r))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to render share card", "id", id, "error", err)
+			apierror.Write(w, r, apierror.New(http.StatusInternalServerError, "share_failed", "Failed to render share card"))
+			return
+		}
 
-	// Extract og:image from the film page
-	c.OnHTML("meta[property='og:image']", func(e *colly.HTMLElement) {
-		img := e.Attr("content")
-		if img != "" {
-			mu.Lock()
-			posterURL = img
-			mu.Unlock()
-			log.Printf("DEBUG: Found og:image: %s", img)
+		// A pick never changes, so the card can be cached for a long time
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Header().Set("Content-Length", strconv.Itoa(len(card)))
+		w.Write(card)
+	}
+}
+
+// sharePoster loads a pick's poster through the image cache, falling back to the placeholder
+func (s *server) sharePoster(ctx context.Context, pick *store.Pick) image.Image {
+	img := s.images.Placeholder(500)
+	if slug := scraper.FilmSlug(pick.FilmURL); scraper.IsFilmSlug(slug) {
+		cached, err := s.images.Get(ctx, slug, 500, func(ctx context.Context) (string, error) {
+			if pick.Image != "" {
+				return pick.Image, nil
+			}
+			result, err := s.posters.Resolve(ctx, scraper.PosterQuery{FilmURL: pick.FilmURL, Title: pick.Name, Year: pick.Year})
+			if err != nil {
+				return "", err
+			}
+			return result.URL, nil
+		})
+		if err == nil {
+			img = cached
 		}
+	}
+
+	poster, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		slog.DebugContext(ctx, "Failed to decode poster for share", "id", pick.ID, "error", err)
+		return nil
+	}
+	return poster
+}
+
+// publicBaseURL is the origin links to this server should use. Without a configured base it
+// is worked out from the request, believing X-Forwarded-Proto and X-Forwarded-Host only from
+// trusted proxies.
+func (s *server) publicBaseURL(r *http.Request) string {
+	if s.cfg.PublicBaseURL != "" {
+		return strings.TrimSuffix(s.cfg.PublicBaseURL, "/")
+	}
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if s.clientIPs.FromTrustedProxy(r) {
+		if proto := forwardedValue(r, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if fwd := forwardedValue(r, "X-Forwarded-Host"); validHost(fwd) {
+			host = fwd
+		}
+	}
+	if !validHost(host) {
+		host = "localhost"
+	}
+	return scheme + "://" + host
+}
+
+// forwardedValue is the last value of a forwarding header, the one written by the proxy that
+// connected to us; earlier values may have come from the client
+func forwardedValue(r *http.Request, header string) string {
+	values := r.Header.Values(header)
+	if len(values) == 0 {
+		return ""
+	}
+	last := values[len(values)-1]
+	if i := strings.LastIndex(last, ","); i >= 0 {
+		last = last[i+1:]
+	}
+	return strings.ToLower(strings.TrimSpace(last))
+}
+
+// validHost reports whether host is a plain "name" or "name:port", with nothing that could
+// turn a link into a different URL
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune(".-:[]", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// filmCacheTTL is how long scraped film details are reused before Letterboxd is asked again
+const filmCacheTTL = 7 * 24 * time.Hour
+
+// filmDetails returns a Letterboxd film's details from the store, scraping and caching them
+// when they are missing or stale
+func (s *server) filmDetails(ctx context.Context, filmURL string) (*scraper.FilmDetails, error) {
+	slug := scraper.FilmSlug(filmURL)
+	if cached, err := s.db.GetFilm(ctx, slug); err == nil && time.Since(cached.FetchedAt) < filmCacheTTL {
+		metrics.CacheLookup("film", true)
+		return &scraper.FilmDetails{
+			URL:      filmURL,
+			Title:    cached.Name,
+			Year:     cached.Year,
+			Runtime:  cached.Runtime,
+			Image:    cached.Image,
+			Overview: cached.Overview,
+		}, nil
+	}
+	metrics.CacheLookup("film", false)
+
+	details, err := scraper.GetFilmDetails(ctx, filmURL)
+	if err != nil {
+		return nil, err
+	}
+	if slug != "" {
+		err := s.db.PutFilm(ctx, &store.Film{
+			Slug:     slug,
+			Name:     details.Title,
+			Year:     details.Year,
+			Overview: details.Overview,
+			Runtime:  details.Runtime,
+			Image:    details.Image,
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to cache film", "slug", slug, "error", err)
+		}
+	}
+	return details, nil
+}
+
+// recordPick saves a pick to the history and returns its share ID, or "" if it couldn't be saved
+func (s *server) recordPick(ctx context.Context, pick *store.Pick) string {
+	if err := s.db.AddPick(ctx, pick); err != nil {
+		slog.WarnContext(ctx, "Failed to record pick", "film", pick.Name, "error", err)
+		return ""
+	}
+	return pick.ID
+}
+
+// historyHandler lists a user's past watchlist picks, newest first
+func (s *server) historyHandler(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
+	if username == "" {
+		apierror.Write(w, r, apierror.ErrUsernameRequired)
+		return
+	}
+	r = withUsername(r, username)
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, "invalid_limit", "limit must be a number between 1 and 100"))
+			return
+		}
+		limit = n
+	}
+
+	picks, err := s.db.ListPicks(r.Context(), username, limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list picks", "error", err)
+		apierror.Write(w, r, apierror.New(http.StatusInternalServerError, "history_failed", "Failed to load history"))
+		return
+	}
+
+	for i := range picks {
+		picks[i].Image = s.posterOrPlaceholder(r, picks[i].FilmURL, picks[i].Image)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(picks)
+}
+
+// resolvePoster runs the poster chain, returning "" when no source has a poster
+func (s *server) resolvePoster(ctx context.Context, q scraper.PosterQuery) string {
+	result, err := s.posters.Resolve(ctx, q)
+	if err != nil {
+		slog.DebugContext(ctx, "No poster found", "film", q.Title, "url", q.FilmURL, "error", err)
+		return ""
+	}
+	return result.URL
+}
+
+// posterOrPlaceholder returns imageURL, or when it is empty the film's poster on this server,
+// which is the bundled placeholder until a source has one
+func (s *server) posterOrPlaceholder(r *http.Request, filmURL, imageURL string) string {
+	slug := scraper.FilmSlug(filmURL)
+	if imageURL != "" || !scraper.IsFilmSlug(slug) {
+		return imageURL
+	}
+	return s.publicBaseURL(r) + apiPrefix + "/posters/" + slug
+}
+
+// posterPreview returns the blurhash and palette of a film's poster, caching the poster under
+// the film's slug so /poster serves the same image. It returns nil when there is nothing to show.
+func (s *server) posterPreview(ctx context.Context, filmURL, imageURL string) *imagecache.Preview {
+	slug := scraper.FilmSlug(filmURL)
+	if !scraper.IsFilmSlug(slug) || imageURL == "" {
+		return nil
+	}
+
+	preview, err := s.images.Preview(ctx, slug, func(context.Context) (string, error) {
+		return imageURL, nil
 	})
+	if err != nil {
+		slog.DebugContext(ctx, "No poster preview", "slug", slug, "error", err)
+		return nil
+	}
+	return preview
+}
 
-	// Visit film detail page
-	log.Printf("DEBUG: Visiting: %s", letterboxdURL)
-	c.Visit(letterboxdURL)
-	c.Wait()
+// writeAIError maps errors from the ai package to responses. It returns false if err is nil.
+func (s *server) writeAIError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if err == nil {
+		return false
+	}
+	if refusal, ok := ai.IsPolicyError(err); ok {
+		slog.InfoContext(r.Context(), "Refused prompt", "code", refusal.Code, "client", usage.ClientFrom(r.Context()))
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, refusal.Code, refusal.Message))
+		return true
+	}
+	if errors.Is(err, ai.ErrUnknownMode) {
+		apierror.Write(w, r, apierror.Newf(http.StatusBadRequest, "invalid_mode", "Unknown mode, expected one of: %s", strings.Join(s.prompts.RecommendModes(), ", ")).
+			WithDetails(map[string][]string{"modes": s.prompts.RecommendModes()}))
+		return true
+	}
+	if errors.Is(err, ai.ErrInvalidOverride) {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, "invalid_override", err.Error()))
+		return true
+	}
+	if errors.Is(err, usage.ErrBudgetExhausted) {
+		apierror.Write(w, r, apierror.New(http.StatusTooManyRequests, "budget_exhausted", "Daily AI token budget exhausted, please try again tomorrow"))
+		return true
+	}
+	if errors.Is(err, ai.ErrNotConfigured) {
+		apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, "ai_not_configured", "AI recommendations are not configured"))
+		return true
+	}
 
-	return posterURL
+	// The cause is logged rather than returned, it can hold upstream details clients should not see
+	slog.ErrorContext(r.Context(), "Failed to get recommendation", "error", err)
+	apierror.Write(w, r, apierror.New(http.StatusInternalServerError, "recommendation_failed", "Failed to get recommendation"))
+	return true
 }
 
-func main() {
-	// Add panic recovery for the entire main function
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("PANIC in main: %v", r)
+func (s *server) marathonHandler(w http.ResponseWriter, r *http.Request) {
+	theme := r.URL.Query().Get("theme")
+	if theme == "" {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, "theme_required", "Theme parameter is required"))
+		return
+	}
+
+	opts := ai.RecommendOptions{
+		Taste: r.URL.Query().Get("taste"),
+		Count: 3,
+	}
+	if count := r.URL.Query().Get("count"); count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < ai.MinMarathonFilms || n > ai.MaxMarathonFilms {
+			apierror.Write(w, r, apierror.Newf(http.StatusBadRequest, "invalid_count", "count must be a number between %d and %d", ai.MinMarathonFilms, ai.MaxMarathonFilms))
+			return
 		}
-	}()
+		opts.Count = n
+	}
 
-	// Set up logging based on environment
-	env := os.Getenv("NODE_ENV")
-	if env == "production" {
-		log.SetFlags(log.LstdFlags | log.Lshortfile)
-		log.Printf("INFO: Starting Go API server in production mode")
-	} else {
-		log.Printf("DEBUG: Starting Go API server in development mode")
+	// Optionally only programme films from the user's watchlist
+	username := r.URL.Query().Get("username")
+	var watchlist map[string]bool
+	if username != "" {
+		r = withUsername(r, username)
+		films, err := s.state.cachedWatchlist(r.Context(), username+":", func(ctx context.Context) ([]scraper.Film, error) {
+			return scraper.ScrapeWatchlistContext(ctx, username, "")
+		})
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to scrape watchlist", "error", err)
+			apierror.Write(w, r, apierror.ErrWatchlistFailed)
+			return
+		}
+		if len(films) < ai.MinMarathonFilms {
+			apierror.Write(w, r, apierror.New(http.StatusNotFound, "not_enough_films", "Not enough films in watchlist for a marathon"))
+			return
+		}
+
+		watchlist = make(map[string]bool, len(films))
+		for _, film := range films {
+			watchlist[strings.TrimSuffix(film.Slug, "/")] = true
+			opts.Candidates = append(opts.Candidates, fmt.Sprintf("%s (%s)", film.Name, film.Year))
+		}
 	}
 
-	// Check if GEMINI_API_KEY is set
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Printf("WARNING: GEMINI_API_KEY not set - AI recommendations will fail")
-	} else {
-		log.Printf("INFO: GEMINI_API_KEY is set (length: %d)", len(apiKey))
+	slog.DebugContext(r.Context(), "Marathon request", "theme", theme, "count", opts.Count)
+
+	if s.recommender == nil {
+		s.writeAIError(w, r, ai.ErrNotConfigured)
+		return
+	}
+	ctx := usage.WithClient(r.Context(), s.clientID(r))
+	r = r.WithContext(ctx)
+	marathon, err := s.recommender.Marathon(ctx, theme, opts)
+	if s.writeAIError(w, r, err) {
+		return
+	}
+
+	// Check every film against Letterboxd, in parallel, keeping the watch order
+	verified := make([]*ai.MarathonFilm, len(marathon.Films))
+	var wg sync.WaitGroup
+	for i := range marathon.Films {
+		film := &marathon.Films[i]
+		if watchlist != nil && !watchlist[strings.TrimSuffix(film.Slug, "/")] {
+			slog.DebugContext(r.Context(), "Dropping film not on watchlist", "film", film.Name)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, film *ai.MarathonFilm) {
+			defer wg.Done()
+			details, err := s.filmDetails(ctx, film.Slug)
+			if err != nil {
+				slog.DebugContext(ctx, "Dropping film from marathon", "film", film.Name, "error", err)
+				return
+			}
+			if details.Runtime > 0 {
+				film.Runtime = details.Runtime
+			}
+			film.Image = details.Image
+			if film.Image == "" {
+				film.Image = s.resolvePoster(ctx, scraper.PosterQuery{
+					FilmURL: film.Slug,
+					Title:   film.Name,
+					Year:    film.Year,
+					TMDBID:  film.TMDBID,
+				})
+			}
+			if film.Year == "" {
+				film.Year = details.Year
+			}
+			if preview := s.posterPreview(ctx, film.Slug, film.Image); preview != nil {
+				film.Blurhash = preview.Blurhash
+				film.Palette = preview.Palette
+			}
+			verified[i] = film
+		}(i, film)
+	}
+	wg.Wait()
+
+	var films []ai.MarathonFilm
+	for _, film := range verified {
+		if film != nil {
+			film.Image = s.posterOrPlaceholder(r, film.Slug, film.Image)
+			films = append(films, *film)
+		}
+	}
+	if len(films) < ai.MinMarathonFilms {
+		apierror.Write(w, r, apierror.New(http.StatusBadGateway, "not_enough_films", "Could not find enough real films for this theme, please try again"))
+		return
 	}
 
-	// Set up routes with production middleware
-	http.HandleFunc("/health", withLogging(withCORS(healthHandler)))
-	http.HandleFunc("/watchlist", withLogging(withRateLimit(withCORS(watchlistHandler))))
-	http.HandleFunc("/recommend", withLogging(withRateLimit(withCORS(recommendHandler))))
+	marathon.Films = films
+	marathon.Tally()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(marathon)
+}
+
+// usageHandler reports AI token usage per day and per client. It requires the admin token as a bearer token.
+func (s *server) usageHandler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			apierror.Write(w, r, apierror.New(http.StatusNotFound, "admin_disabled", "Admin endpoints are disabled"))
+			return
+		}
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			apierror.Write(w, r, apierror.ErrUnauthorized)
+			return
+		}
 
-	// Default route with CORS
-	http.HandleFunc("/", withLogging(withCORS(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"message":     "Go API Server is running",
-			"endpoints":   []string{"/health", "/watchlist", "/recommend"},
-			"version":     "1.0.0",
-			"environment": env,
-		})
-	})))
+		json.NewEncoder(w).Encode(s.ledger.Report())
+	}
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8081" // Different port from Express server
+// metricsHandler serves Prometheus metrics, behind a bearer token when one is configured
+func metricsHandler(token string) http.HandlerFunc {
+	h := metrics.Handler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				apierror.Write(w, r, apierror.ErrUnauthorized)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	}
+}
+
+// parseOverrides reads optional temperature, top_p, top_k and max_output_tokens query parameters.
+// Ranges are checked by the recommender against its configuration.
+func parseOverrides(r *http.Request) (ai.Overrides, error) {
+	var o ai.Overrides
+	q := r.URL.Query()
+
+	if v := q.Get("temperature"); v != "" {
+		f, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return o, fmt.Errorf("temperature must be a number")
+		}
+		t := float32(f)
+		o.Temperature = &t
+	}
+	if v := q.Get("top_p"); v != "" {
+		f, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return o, fmt.Errorf("top_p must be a number")
+		}
+		p := float32(f)
+		o.TopP = &p
+	}
+	if v := q.Get("top_k"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return o, fmt.Errorf("top_k must be an integer")
+		}
+		k := int32(n)
+		o.TopK = &k
+	}
+	if v := q.Get("max_output_tokens"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return o, fmt.Errorf("max_output_tokens must be an integer")
+		}
+		m := int32(n)
+		o.MaxOutputTokens = &m
 	}
 
-	log.Printf("INFO: Go API server ready on port %s", port)
-	log.Printf("INFO: Environment: %s", env)
-	log.Printf("INFO: Available endpoints:")
-	log.Printf("  - GET /health")
-	log.Printf("  - GET /watchlist?username=<username>&genres=<genres>")
-	log.Printf("  - GET /recommend?prompt=<prompt>")
+	return o, nil
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	os.Exit(runCommand(args))
+}
 
-	// Start server with error handling
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Printf("FATAL: Failed to start server: %v", err)
-		os.Exit(1)
+// serve runs the API server until it fails. args are configuration flags, see config.Load.
+func serve(args []string) error {
+	cfg, rest, err := config.Load(flag.NewFlagSet("serve", flag.ContinueOnError), args, configChecks...)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("serve takes no arguments, got %q", rest)
+	}
+	if err := logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
 	}
+
+	// Spans are flushed last, once the server has stopped
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:       cfg.Tracing.Exporter,
+		Endpoint:       cfg.Tracing.Endpoint,
+		SampleRatio:    cfg.Tracing.SampleRatio,
+		ServiceName:    "go-backend",
+		ServiceVersion: version.Get().String(),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
+	// SIGTERM (docker stop, deploys) and SIGINT (Ctrl-C) start a graceful shutdown. Once it has
+	// started the handlers are restored, so a second signal kills the process straight away.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	// Add panic recovery for the entire server
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Panic in serve", "panic", r)
+		}
+	}()
+
+	slog.Info("Starting Go API server", "version", version.Get().String(), "env", cfg.Env, "log_level", cfg.Log.Level)
+
+	srv, err := newServer(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	// Closed once in-flight requests have finished, see server.Close
+	defer srv.Close()
+	go srv.prompts.Watch(ctx, 5*time.Second)
+	go srv.expireSessions(ctx, time.Hour)
+
+	slog.Info("Go API server ready", "addr", cfg.Addr(), "env", cfg.Env)
+
+	return runServer(ctx, cfg.Addr(), cfg.Server, srv.router())
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-backend/internal/config"
+	"go-backend/internal/ratelimit"
+)
+
+func TestRateLimitPolicies(t *testing.T) {
+	cfg := config.RateLimit{
+		Enabled:   true,
+		Requests:  3,
+		Window:    time.Minute,
+		Recommend: config.Budget{Requests: 1, Window: time.Hour},
+		Watchlist: config.Budget{Requests: 2, Window: time.Minute},
+	}
+	newLimiter := func(p ratelimit.Policy) ratelimit.Limiter { return ratelimit.NewMemory(p) }
+	clientIP := func(r *http.Request) string { return r.Header.Get("X-Test-Client") }
+	withRateLimit := newRateLimit(cfg, newLimiter, clientIP)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	handlers := map[string]http.HandlerFunc{
+		policyDefault:   withRateLimit(policyDefault, ok),
+		policyRecommend: withRateLimit(policyRecommend, ok),
+		policyWatchlist: withRateLimit(policyWatchlist, ok),
+		"":              withRateLimit("", ok),
+	}
+	call := func(policy, client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Test-Client", client)
+		rec := httptest.NewRecorder()
+		handlers[policy](rec, req)
+		return rec
+	}
+
+	// Each policy has its own budget, and spending one leaves the others untouched
+	for policy, allowed := range map[string]int{policyRecommend: 1, policyWatchlist: 2, policyDefault: 3} {
+		for i := range allowed {
+			rec := call(policy, "alice")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s request %d = %d, want 200", policy, i+1, rec.Code)
+			}
+			if got, want := rec.Header().Get("RateLimit-Limit"), allowed; got != strconv.Itoa(want) {
+				t.Errorf("%s RateLimit-Limit = %q, want %d", policy, got, want)
+			}
+		}
+		rec := call(policy, "alice")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s request %d = %d, want 429", policy, allowed+1, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") == "" || rec.Header().Get("RateLimit-Remaining") != "0" {
+			t.Errorf("%s refusal headers = %v", policy, rec.Header())
+		}
+	}
+
+	// Clients are limited separately
+	if rec := call(policyRecommend, "bob"); rec.Code != http.StatusOK {
+		t.Errorf("bob's first recommend = %d, want 200", rec.Code)
+	}
+
+	// Routes without a policy are never limited
+	for range 5 {
+		if rec := call("", "alice"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("unlimited route = %d with headers %v", rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	budget := config.Budget{Requests: 1, Window: time.Hour}
+	cfg := config.RateLimit{Requests: 1, Window: time.Hour, Recommend: budget, Watchlist: budget}
+	newLimiter := func(p ratelimit.Policy) ratelimit.Limiter { return ratelimit.NewMemory(p) }
+	h := newRateLimit(cfg, newLimiter, func(*http.Request) string { return "alice" })(policyDefault, func(http.ResponseWriter, *http.Request) {})
+
+	for range 3 {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("disabled rate limit answered %d", rec.Code)
+		}
+	}
+}
+
+func TestPickedFor(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"  Sam  ", "Sam"},
+		{"Sam\x00\u202e\u200b and\tAlex", "Sam and Alex"},
+		{strings.Repeat("é", 50), strings.Repeat("é", maxPickedForLength)},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/?for="+url.QueryEscape(tt.query), nil)
+		if got := pickedFor(r); got != tt.want {
+			t.Errorf("pickedFor(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
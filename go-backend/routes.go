@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	"go-backend/internal/apierror"
+	"go-backend/internal/logging"
+	"go-backend/internal/tracing"
+	"go-backend/internal/version"
+)
+
+// apiPrefix is where the current version of the API is served
+const apiPrefix = "/api/v1"
+
+// route is one endpoint. Legacy is the unversioned path it used to be served at, kept as a
+// deprecated alias that reads the path parameters from the query string instead. Internal
+// endpoints are never called from a browser, so they get no CORS headers. RateLimit names the
+// rate limit policy the route counts against, none when empty.
+type route struct {
+	Method    string
+	Path      string
+	Legacy    string
+	RateLimit string
+	Internal  bool
+	Handler   http.HandlerFunc
+}
+
+// routes lists every endpoint the server exposes
+func (s *server) routes() []route {
+	cfg := s.cfg
+	rts := []route{
+		{Method: "GET", Path: apiPrefix + "/health", Legacy: "/health", Handler: healthHandler(cfg.Env)},
+		{Method: "GET", Path: apiPrefix + "/watchlist/{username}", Legacy: "/watchlist", RateLimit: policyWatchlist, Handler: s.watchlistHandler},
+		{Method: "GET", Path: apiPrefix + "/watchlist/{username}/export", Legacy: "/watchlist/export", RateLimit: policyWatchlist, Handler: s.watchlistExportHandler},
+		{Method: "GET", Path: apiPrefix + "/recommend", Legacy: "/recommend", RateLimit: policyRecommend, Handler: s.recommendHandler},
+		{Method: "GET", Path: apiPrefix + "/marathon", Legacy: "/marathon", RateLimit: policyRecommend, Handler: s.marathonHandler},
+		{Method: "GET", Path: apiPrefix + "/posters/{slug}", Legacy: "/poster/{slug}", Handler: s.posterHandler},
+		{Method: "GET", Path: apiPrefix + "/history/{username}", Legacy: "/history", RateLimit: policyDefault, Handler: s.historyHandler},
+		{Method: "GET", Path: apiPrefix + "/admin/usage", Legacy: "/admin/usage", Internal: true, Handler: s.usageHandler(cfg.AdminToken)},
+		// Share links are pasted into chat apps, so they stay short and unversioned
+		{Method: "GET", Path: "/share/{id}", Handler: s.shareHandler()},
+		// Probes for orchestrators, at the paths they expect
+		{Method: "GET", Path: "/healthz", Internal: true, Handler: healthHandler(cfg.Env)},
+		{Method: "GET", Path: "/readyz", Internal: true, Handler: readyHandler(s.newReadiness())},
+	}
+	// Prometheus expects /metrics, so it is unversioned too
+	if cfg.Metrics.Enabled {
+		rts = append(rts, route{Method: "GET", Path: "/metrics", Internal: true, Handler: metricsHandler(cfg.Metrics.Token)})
+	}
+	return rts
+}
+
+// router registers every route, its deprecated alias and CORS preflight on a new mux.
+// Unknown paths get a JSON 404 and known paths with the wrong method a JSON 405 with Allow.
+func (s *server) router() http.Handler {
+	cfg := s.cfg
+	withCORS := newCORS(cfg.AllowedOrigins)
+	withRateLimit := newRateLimit(cfg.RateLimit, s.state.limiter, s.clientIP)
+
+	mux := http.NewServeMux()
+	var endpoints []string
+	for _, rt := range s.routes() {
+		h := withRateLimit(rt.RateLimit, rt.Handler)
+		if !rt.Internal {
+			h = withCORS(h)
+		}
+
+		mux.HandleFunc(rt.Method+" "+rt.Path, withLogging(h))
+		if rt.Legacy != "" {
+			mux.HandleFunc(rt.Method+" "+rt.Legacy, withLogging(deprecated(rt.Path, h)))
+		}
+		endpoints = append(endpoints, rt.Method+" "+rt.Path)
+	}
+
+	// Preflight requests for any path, answered by the CORS middleware
+	mux.HandleFunc("OPTIONS /", withCORS(func(w http.ResponseWriter, r *http.Request) {}))
+
+	mux.HandleFunc("GET /{$}", withLogging(withCORS(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     "Go API Server is running",
+			"endpoints":   endpoints,
+			"version":     version.Get().Version,
+			"environment": cfg.Env,
+		})
+	})))
+
+	return tracing.Handler(&router{mux: mux, notFound: withLogging(withCORS(routeNotFound))})
+}
+
+// router serves a mux, answering requests no pattern matches itself so the errors are JSON
+type router struct {
+	mux      *http.ServeMux
+	notFound http.HandlerFunc
+}
+
+// probeMethods are tried against a path to build the Allow header of a 405
+var probeMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = withRequestID(w, r)
+
+	// A panicking handler answers with the usual error body instead of a dropped connection
+	defer func() {
+		if p := recover(); p != nil {
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			slog.ErrorContext(r.Context(), "Handler panicked", "method", r.Method, "path", r.URL.Path, "panic", p, "stack", string(debug.Stack()))
+			apierror.Write(w, r, apierror.ErrInternal)
+		}
+	}()
+
+	if _, pattern := rt.mux.Handler(r); pattern != "" {
+		rt.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// The preflight route matches every path, so it only counts alongside another method
+	var allowed []string
+	for _, method := range probeMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := rt.mux.Handler(probe); pattern != "" && pattern != "OPTIONS /" {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		rt.notFound(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(append(allowed, "OPTIONS"), ", "))
+	withLogging(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.ErrMethodNotAllowed.WithDetails(map[string][]string{"allow": allowed}))
+	})(w, r)
+}
+
+// requestIDPattern is what an incoming X-Request-ID must look like to be reused, anything else
+// could forge or garble log lines
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// withRequestID tags a request with the X-Request-ID it came with, from a proxy or client, or a
+// new random one. The ID is echoed in the response, in error bodies and on every log line,
+// alongside the trace ID when the request is being traced.
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := r.Header.Get(apierror.RequestIDHeader)
+	if !requestIDPattern.MatchString(id) {
+		id = newRequestID()
+		r.Header.Set(apierror.RequestIDHeader, id)
+	}
+	w.Header().Set(apierror.RequestIDHeader, id)
+	ctx := logging.WithRequestID(r.Context(), id)
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		ctx = logging.With(ctx, slog.String("trace_id", traceID))
+	}
+	return r.WithContext(ctx)
+}
+
+// newRequestID returns 16 random hex characters
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func routeNotFound(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.ErrNotFound.WithDetails(map[string]string{"path": r.URL.Path}))
+}
+
+// pathParamPattern matches the {name} segments of a route path
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// deprecated marks responses from a legacy alias, pointing at its versioned successor with
+// the parameters filled in, e.g. /watchlist?username=alice -> /api/v1/watchlist/alice
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := pathParamPattern.ReplaceAllStringFunc(successor, func(m string) string {
+			name := m[1 : len(m)-1]
+			return url.PathEscape(pathParam(r, name))
+		})
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+link+`>; rel="successor-version"`)
+		h(w, r)
+	}
+}
+
+// pathParam reads a path parameter, falling back to the query string for legacy aliases that
+// took it there, e.g. /watchlist?username=alice
+func pathParam(r *http.Request, name string) string {
+	if v := r.PathValue(name); v != "" {
+		return v
+	}
+	return r.URL.Query().Get(name)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-backend/internal/ai"
+	"go-backend/internal/clientip"
+	"go-backend/internal/config"
+	"go-backend/internal/imagecache"
+	"go-backend/internal/logging"
+	"go-backend/internal/scraper"
+	"go-backend/internal/store"
+	"go-backend/internal/tmdb"
+	"go-backend/internal/tracing"
+	"go-backend/internal/usage"
+)
+
+// server is everything the handlers share, built once from the configuration by newServer
+type server struct {
+	cfg *config.Config
+
+	// prompts are the recommend prompt templates
+	prompts *ai.PromptStore
+	// recommender is nil when no Gemini API key is configured
+	recommender *ai.Recommender
+	// posters are the poster sources, tried in order
+	posters *scraper.PosterChain
+	// images is the on-disk poster cache behind /posters/{slug}
+	images *imagecache.Cache
+	// db holds users, cached films, pick history, sessions, API keys and AI usage
+	db store.Store
+	// ledger accounts for AI tokens, shared by every recommend request
+	ledger *usage.Ledger
+	// clientIPs works out client IPs, believing X-Forwarded-For only from trusted proxies
+	clientIPs *clientip.Resolver
+	// state is where rate limit buckets and cached watchlists live
+	state *stateBackend
+
+	// closers release what newServer opened, run in reverse
+	closers []func()
+}
+
+// newServer connects to everything the configuration names. On error whatever was already
+// opened is closed again.
+func newServer(ctx context.Context, cfg *config.Config) (_ *server, err error) {
+	s := &server{cfg: cfg}
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+
+	s.clientIPs, err = clientip.New(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PublicBaseURL == "" {
+		slog.Warn("PUBLIC_BASE_URL not set, share and poster links are built from each request's Host header")
+	}
+
+	// Load prompt templates, from disk when a template directory is set so they can be edited live
+	s.prompts, err = ai.LoadPrompts(cfg.Prompts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+	slog.Info("Prompt templates loaded", "modes", s.prompts.Modes())
+
+	// Poster sources, tried in order, e.g. POSTER_SOURCES=letterboxd-ajax:5s,tmdb-id:3s
+	var tmdbClient *tmdb.Client
+	if cfg.TMDB.AccessToken != "" {
+		var opts []tmdb.Option
+		if cfg.TMDB.BaseURL != "" {
+			opts = append(opts, tmdb.WithBaseURL(cfg.TMDB.BaseURL))
+		}
+		opts = append(opts, tmdb.WithHTTPClient(tracing.HTTPClient(10*time.Second)))
+		tmdbClient = tmdb.New(cfg.TMDB.AccessToken, opts...)
+	} else {
+		slog.Warn("TMDB poster sources will be skipped", "error", tmdb.ErrNotConfigured)
+	}
+	s.posters, err = scraper.ParsePosterChain(cfg.Posters.Sources, 5*time.Second, tmdbClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid poster sources: %w", err)
+	}
+	slog.Info("Poster sources", "sources", s.posters.Sources())
+
+	// Poster images are cached on disk, by content hash
+	s.images, err = imagecache.New(cfg.Posters.CacheDir, tracing.HTTPClient(15*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Poster cache", "dir", cfg.Posters.CacheDir)
+
+	// Everything that should survive a restart lives in one SQLite file
+	sqliteStore, err := store.OpenSQLite(ctx, cfg.StorePath)
+	if err != nil {
+		return nil, err
+	}
+	s.db = sqliteStore
+	s.closers = append(s.closers, func() {
+		if err := sqliteStore.Close(); err != nil {
+			slog.Error("Failed to close store", "error", err)
+			return
+		}
+		slog.Info("Store closed")
+	})
+	slog.Info("Store opened", "path", cfg.StorePath)
+
+	// Token accounting, with an optional cap on how many tokens can be spent per day
+	s.ledger = usage.NewLedger(cfg.DailyTokenBudget)
+	if err := s.ledger.Persist(ctx, sqliteStore); err != nil {
+		return nil, fmt.Errorf("failed to load AI usage: %w", err)
+	}
+
+	// One Gemini client for the lifetime of the server
+	aiCfg := aiConfig(cfg)
+	recommender, err := ai.NewRecommender(ctx, aiCfg, s.prompts)
+	switch {
+	case errors.Is(err, ai.ErrNotConfigured):
+		slog.Warn("GEMINI_API_KEY not set, AI recommendations will fail")
+	case err != nil:
+		return nil, fmt.Errorf("failed to create recommender: %w", err)
+	default:
+		recommender.TrackUsage(s.ledger)
+		s.recommender = recommender
+		s.closers = append(s.closers, func() { recommender.Close() })
+		slog.Info("Gemini configured", "model", aiCfg.Model)
+	}
+
+	// Rate limits and cached watchlists, shared between instances with the redis backend
+	s.state, err = openState(ctx, cfg.State)
+	if err != nil {
+		return nil, err
+	}
+	s.closers = append(s.closers, func() { s.state.Close() })
+	slog.Info("State backend", "backend", cfg.State.Backend, "watchlist_ttl", cfg.State.WatchlistTTL)
+
+	return s, nil
+}
+
+// Close releases everything newServer opened, the store last
+func (s *server) Close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+	s.closers = nil
+}
+
+// expireSessions deletes expired sessions from the store every interval until ctx ends
+func (s *server) expireSessions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := s.db.DeleteExpiredSessions(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to delete expired sessions", "error", err)
+			continue
+		}
+		if n > 0 {
+			slog.InfoContext(ctx, "Expired sessions deleted", "sessions", n)
+		}
+	}
+}
+
+// configChecks validate the settings other packages interpret, see config.Check
+var configChecks = []config.Check{
+	{Key: "log.level", Run: func(c *config.Config) error {
+		_, err := logging.ParseLevel(c.Log.Level)
+		return err
+	}},
+	{Key: "log.format", Run: func(c *config.Config) error { return oneOf(c.Log.Format, logging.Formats) }},
+	{Key: "tracing.exporter", Run: func(c *config.Config) error { return oneOf(c.Tracing.Exporter, tracing.Exporters) }},
+	{Key: "trusted_proxies", Run: func(c *config.Config) error {
+		_, err := clientip.ParsePrefixes(c.TrustedProxies)
+		return err
+	}},
+	{Key: "gemini", Run: func(c *config.Config) error { return aiConfig(c).Validate() }},
+	{Key: "posters.sources", Run: func(c *config.Config) error {
+		_, err := scraper.ParsePosterChain(c.Posters.Sources, time.Second, nil)
+		return err
+	}},
+}
+
+// oneOf fails unless v is one of allowed
+func oneOf(v string, allowed []string) error {
+	if slices.Contains(allowed, v) {
+		return nil
+	}
+	return fmt.Errorf("must be one of %s, got %q", strings.Join(allowed, ", "), v)
+}
+
+// aiConfig is the recommender's share of the configuration
+func aiConfig(c *config.Config) ai.Config {
+	return ai.Config{
+		APIKey:          c.Gemini.APIKey,
+		Model:           c.Gemini.Model,
+		Temperature:     c.Gemini.Temperature,
+		TopP:            c.Gemini.TopP,
+		TopK:            c.Gemini.TopK,
+		MaxOutputTokens: c.Gemini.MaxOutputTokens,
+		MaxPromptLength: c.Prompts.MaxLength,
+	}
+}
+
+// cancelGrace is how long cancelled handlers get to return before the server gives up on them
+const cancelGrace = 5 * time.Second
+
+// runServer serves handler on addr until ctx is done, then shuts down gracefully: it stops
+// accepting connections, gives in-flight requests cfg.ShutdownTimeout to finish, then cancels
+// their contexts so scrapes and model calls stop, and waits for the handlers to return
+func runServer(ctx context.Context, addr string, cfg config.Server, handler http.Handler) error {
+	// Every request context derives from base, so cancelling it stops all in-flight work
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
+	var inflight sync.WaitGroup
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inflight.Add(1)
+			defer inflight.Done()
+			handler.ServeHTTP(w, r)
+		}),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return base },
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("failed to start server: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, waiting for in-flight requests", "timeout", cfg.ShutdownTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(drainCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("Requests still running, cancelling them", "after", cfg.ShutdownTimeout)
+	} else if err != nil {
+		slog.Warn("Shutdown failed", "error", err)
+	}
+
+	cancelBase()
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(cancelGrace):
+		slog.Warn("Some requests did not stop after being cancelled", "within", cancelGrace)
+	}
+	srv.Close()
+
+	slog.Info("Server stopped")
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go-backend/internal/cache"
+	"go-backend/internal/config"
+	"go-backend/internal/metrics"
+	"go-backend/internal/ratelimit"
+	"go-backend/internal/scraper"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// stateBackend is where rate limit buckets and cached watchlists live, see config.State
+type stateBackend struct {
+	// redis is nil for the memory backend
+	redis  *redis.Client
+	prefix string
+
+	watchlists   cache.Cache
+	watchlistTTL time.Duration
+}
+
+// openState connects to the configured backend. Redis has to answer at startup, so a wrong
+// URL fails the deploy rather than every instance quietly limiting on its own.
+func openState(ctx context.Context, cfg config.State) (*stateBackend, error) {
+	s := &stateBackend{prefix: cfg.KeyPrefix, watchlistTTL: cfg.WatchlistTTL}
+	if cfg.Backend != "redis" {
+		s.watchlists = cache.NewMemory()
+		return s, nil
+	}
+
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	s.redis = redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := s.redis.Ping(ctx).Err(); err != nil {
+		s.redis.Close()
+		return nil, fmt.Errorf("failed to reach redis at %s: %w", opts.Addr, err)
+	}
+	s.watchlists = cache.NewRedis(s.redis, s.prefix)
+	return s, nil
+}
+
+// limiter returns a limiter enforcing p in the backend
+func (s *stateBackend) limiter(p ratelimit.Policy) ratelimit.Limiter {
+	if s.redis == nil {
+		return ratelimit.NewMemory(p)
+	}
+	return ratelimit.NewRedis(s.redis, s.prefix, p)
+}
+
+// ping checks Redis answers, the memory backend always does
+func (s *stateBackend) ping(ctx context.Context) error {
+	if s.redis == nil {
+		return nil
+	}
+	return s.redis.Ping(ctx).Err()
+}
+
+// Close disconnects from Redis
+func (s *stateBackend) Close() error {
+	if s.redis == nil {
+		return nil
+	}
+	return s.redis.Close()
+}
+
+// cachedWatchlist returns the films cached under key, or scrapes them and caches them for the
+// watchlist TTL. Empty watchlists aren't cached, a failed scrape can look the same. Cache errors
+// are only logged, so a Redis outage costs speed, not requests.
+func (s *stateBackend) cachedWatchlist(ctx context.Context, key string, scrape func(context.Context) ([]scraper.Film, error)) ([]scraper.Film, error) {
+	if s.watchlistTTL <= 0 {
+		return scrape(ctx)
+	}
+	key = "watchlist:" + strings.ToLower(key)
+
+	var films []scraper.Film
+	err := cache.GetJSON(ctx, s.watchlists, key, &films)
+	if err == nil {
+		metrics.CacheLookup("watchlist", true)
+		return films, nil
+	}
+	if !errors.Is(err, cache.ErrMiss) {
+		slog.WarnContext(ctx, "Failed to read watchlist cache", "error", err)
+	}
+	metrics.CacheLookup("watchlist", false)
+
+	films, err = scrape(ctx)
+	if err != nil || len(films) == 0 {
+		return films, err
+	}
+	if err := cache.SetJSON(ctx, s.watchlists, key, films, s.watchlistTTL); err != nil {
+		slog.WarnContext(ctx, "Failed to cache watchlist", "error", err)
+	}
+	return films, nil
+}
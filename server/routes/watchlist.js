@@ -62,12 +62,12 @@ router.get('/', async (req, res) => {
         
         filmWithPoster = {
           ...randomFilm,
-          image: scrapedData.poster || 'https://watchlistpicker.com/noimagefound.jpg',
+          image: scrapedData.poster || '',
           overview: scrapedData.overview || randomFilm.overview
         };
       } catch (error) {
         console.error('Python scraper error:', error.message);
-        filmWithPoster.image = 'https://watchlistpicker.com/noimagefound.jpg';
+        filmWithPoster.image = '';
       }
     }
 
@@ -208,8 +208,8 @@ async def scrape_letterboxd_poster(url, timeout=20):
                 
                 debug_print(f"✓ Final poster URL: {poster_url}")
             else:
-                poster_url = 'https://watchlistpicker.com/noimagefound.jpg'
-                debug_print("✗ No valid poster found, using default")
+                poster_url = ''
+                debug_print("✗ No valid poster found")
             
             # Extract overview
             overview = ""
@@ -246,7 +246,7 @@ async def scrape_letterboxd_poster(url, timeout=20):
     except Exception as e:
         debug_print(f"Scraper error: {e}")
         return {
-            'poster': 'https://watchlistpicker.com/noimagefound.jpg',
+            'poster': '',
             'overview': '',
             'success': False,
             'error': str(e)
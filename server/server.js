@@ -9,7 +9,6 @@ import path from 'path';
 import { fileURLToPath } from 'url';
 
 import watchlistRoutes from './routes/watchlist.js';
-import recommendRoutes from './routes/recommend.js';
 
 dotenv.config();
 
@@ -46,7 +45,6 @@ app.get('/health', (req, res) => {
 
 // API Routes
 app.use('/api/watchlist', watchlistRoutes);
-app.use('/api/recommend', recommendRoutes);
 
 // Python scraper communication helper
 export const callPythonScraper = (url, timeout = 30000) => {